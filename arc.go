@@ -0,0 +1,295 @@
+package container
+
+import (
+	"time"
+)
+
+type (
+	// Arc is the "adaptive replacement cache" container. It keeps recently
+	// used elements (T1 list) separately from the elements used at least twice
+	// (T2 list), and remembers the keys recently evicted from both lists in
+	// the ghost lists (B1 and B2). A hit in a ghost list adapts the target size
+	// of T1, so the cache balances between recency and frequency depending on
+	// the workload.
+	//
+	// Like Lru, Arc controls elements by their size, so the lists sizes are
+	// measured in the units of the size argument of Put, and by time of touch.
+	// It has the same API as Lru (see LruCache).
+	Arc[K comparable, V any] struct {
+		// heads of the lists, see arcT1, arcT2, arcB1 and arcB2
+		heads [4]*lru_element[K, V]
+		sizes [4]int64
+		pool  lru_pool[K, V]
+		kvMap map[K]*lru_element[K, V]
+		// n is the number of the elements in T1 and T2
+		n int
+		// p is the target size of T1
+		p        int64
+		maxSize  int64
+		maxDur   time.Duration
+		cback    TypedLruDeleteCallback[K, V]
+		ecback   LruEvictCallback[K, V]
+		clockNow TsClockNowF
+	}
+)
+
+const (
+	arcT1 = 0
+	arcT2 = 1
+	arcB1 = 2
+	arcB2 = 3
+)
+
+// NewArc creates new Arc container with maximum size maxSize. The to and cback
+// parameters have the same meaning as for NewTypedLru.
+func NewArc[K comparable, V any](maxSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V]) *Arc[K, V] {
+	return NewArcWithClock(maxSize, to, cback, time.Now)
+}
+
+// NewArcWithClock same as NewArc, but allows to provide the clock function
+// clck, which is used for discovering current time instead of time.Now()
+func NewArcWithClock[K comparable, V any](maxSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V], clck TsClockNowF) *Arc[K, V] {
+	a := new(Arc[K, V])
+	a.kvMap = make(map[K]*lru_element[K, V])
+	a.maxSize = maxSize
+	a.maxDur = to
+	a.cback = cback
+	a.clockNow = clck
+	a.pool.max = 1
+	return a
+}
+
+// SetPoolSize sets the maximum number of the deleted elements kept for reuse,
+// see Lru.SetPoolSize
+func (a *Arc[K, V]) SetPoolSize(n int) {
+	a.pool.setSize(n)
+}
+
+// SetEvictCallback sets the callback, see Lru.SetEvictCallback
+func (a *Arc[K, V]) SetEvictCallback(ecback LruEvictCallback[K, V]) {
+	a.ecback = ecback
+}
+
+// Put places the key-value pair into the cache. New keys are placed into T1,
+// the keys which are already in the cache or found in the ghost lists are
+// placed into T2.
+func (a *Arc[K, V]) Put(k K, v V, size int64) {
+	tm := a.SweepByTime()
+	seg := arcT1
+	inB2 := false
+	if e, ok := a.kvMap[k]; ok {
+		seg = arcT2
+		switch e.seg {
+		case arcT1, arcT2:
+			a.delete(e, LruReplaced, true)
+		case arcB1:
+			a.p += arcDelta(size, a.sizes[arcB2], a.sizes[arcB1])
+			if a.p > a.maxSize {
+				a.p = a.maxSize
+			}
+			a.delete(e, LruDeleted, false)
+		case arcB2:
+			inB2 = true
+			a.p -= arcDelta(size, a.sizes[arcB1], a.sizes[arcB2])
+			if a.p < 0 {
+				a.p = 0
+			}
+			a.delete(e, LruDeleted, false)
+		}
+	}
+
+	a.replace(size, inB2)
+
+	var e *lru_element[K, V]
+	e = a.pool.get()
+	e.v.key = k
+	e.v.val = v
+	e.v.ts = tm
+	e.v.size = size
+	e.hidx = -1
+	a.add(e, uint8(seg))
+	a.kvMap[k] = e
+	a.n++
+	a.trimGhosts()
+}
+
+// Get returns the value for k and moves it to the top of T2. Returns nil if
+// there is no such key in the cache.
+func (a *Arc[K, V]) Get(k K) *TypedLruValue[K, V] {
+	ts := a.SweepByTime()
+	e, ok := a.kvMap[k]
+	if !ok || e.seg > arcT2 {
+		return nil
+	}
+	a.remove(e)
+	a.add(e, arcT2)
+	e.v.ts = ts
+	return &e.v
+}
+
+func (a *Arc[K, V]) Peek(k K) *TypedLruValue[K, V] {
+	a.SweepByTime()
+	e, ok := a.kvMap[k]
+	if !ok || e.seg > arcT2 {
+		return nil
+	}
+	return &e.v
+}
+
+func (a *Arc[K, V]) Delete(k K) {
+	a.SweepByTime()
+	if e, ok := a.kvMap[k]; ok {
+		a.delete(e, LruDeleted, true)
+	}
+}
+
+func (a *Arc[K, V]) DeleteNoCallback(k K) {
+	a.SweepByTime()
+	if e, ok := a.kvMap[k]; ok {
+		a.delete(e, LruDeleted, false)
+	}
+}
+
+// Clear deletes all the elements and forgets the ghost keys
+func (a *Arc[K, V]) Clear(cb bool) {
+	for seg := range a.heads {
+		for a.heads[seg] != nil {
+			a.delete(a.heads[seg], LruCleared, cb)
+		}
+	}
+	a.p = 0
+}
+
+// Iterate walks over T2 elements and then over T1 ones, both in LRU order. It
+// calls f() for every key-value pair and continues until the f() returns false,
+// or all elements are visited.
+//
+// Note: the modifications of the container must not allowed in the f
+func (a *Arc[K, V]) Iterate(f TypedLruCallback[K, V]) {
+	for _, seg := range []int{arcT2, arcT1} {
+		head := a.heads[seg]
+		h := head
+		for h != nil {
+			if !f(h.v.key, h.v.val) {
+				return
+			}
+			h = h.next
+			if h == head {
+				break
+			}
+		}
+	}
+}
+
+// Size returns size of the elements in the cache, the ghost keys are not counted
+func (a *Arc[K, V]) Size() int64 {
+	return a.sizes[arcT1] + a.sizes[arcT2]
+}
+
+// Len returns number of the elements in the cache, the ghost keys are not counted
+func (a *Arc[K, V]) Len() int {
+	return a.n
+}
+
+// Target returns current target size of T1 list
+func (a *Arc[K, V]) Target() int64 {
+	return a.p
+}
+
+// SweepByTime pulls out the elements which were not touched for maxDur. The
+// expired elements are not remembered in the ghost lists.
+func (a *Arc[K, V]) SweepByTime() time.Time {
+	if a.maxDur == 0 {
+		return nilTime
+	}
+	tm := a.clockNow()
+	for _, seg := range []int{arcT1, arcT2} {
+		for a.heads[seg] != nil && tm.Sub(a.heads[seg].prev.v.ts) > a.maxDur {
+			a.delete(a.heads[seg].prev, LruExpired, true)
+		}
+	}
+	return tm
+}
+
+// arcDelta returns the target size adaptation for the ghost hit of the element
+// with size, where hitSize is size of the ghost list which was hit.
+func arcDelta(size, otherSize, hitSize int64) int64 {
+	if hitSize > 0 && otherSize > hitSize {
+		return size * otherSize / hitSize
+	}
+	return size
+}
+
+// replace evicts the elements from T1 or T2 to the ghost lists until there is
+// enough space for an element of the size.
+func (a *Arc[K, V]) replace(size int64, inB2 bool) {
+	for a.Size()+size > a.maxSize && a.n > 0 {
+		t1 := a.sizes[arcT1]
+		if a.heads[arcT1] != nil && (t1 > a.p || (inB2 && t1 == a.p) || a.heads[arcT2] == nil) {
+			a.evict(a.heads[arcT1].prev, arcB1)
+		} else {
+			a.evict(a.heads[arcT2].prev, arcB2)
+		}
+	}
+}
+
+// trimGhosts drops the ghost keys so |T1|+|B1| <= c and |T1|+|T2|+|B1|+|B2| <= 2c
+func (a *Arc[K, V]) trimGhosts() {
+	for a.heads[arcB1] != nil && a.sizes[arcT1]+a.sizes[arcB1] > a.maxSize {
+		a.delete(a.heads[arcB1].prev, LruCapacity, false)
+	}
+	for a.sizes[arcT1]+a.sizes[arcT2]+a.sizes[arcB1]+a.sizes[arcB2] > 2*a.maxSize {
+		seg := arcB2
+		if a.heads[seg] == nil {
+			seg = arcB1
+		}
+		if a.heads[seg] == nil {
+			return
+		}
+		a.delete(a.heads[seg].prev, LruCapacity, false)
+	}
+}
+
+// evict pulls the element out of the cache and remembers its key in the ghost list
+func (a *Arc[K, V]) evict(e *lru_element[K, V], ghost uint8) {
+	a.remove(e)
+	a.n--
+	a.notify(e, LruCapacity)
+	var zv V
+	e.v.val = zv
+	a.add(e, ghost)
+}
+
+func (a *Arc[K, V]) add(e *lru_element[K, V], seg uint8) {
+	e.seg = seg
+	a.heads[seg] = addToHead(a.heads[seg], e)
+	a.sizes[seg] += e.v.size
+}
+
+func (a *Arc[K, V]) remove(e *lru_element[K, V]) {
+	a.heads[e.seg] = removeFromList(a.heads[e.seg], e)
+	a.sizes[e.seg] -= e.v.size
+}
+
+// delete removes the element from the cache, or the ghost key. The callbacks
+// are notified only for the elements in T1 and T2.
+func (a *Arc[K, V]) delete(e *lru_element[K, V], r LruEvictReason, cb bool) {
+	a.remove(e)
+	delete(a.kvMap, e.v.key)
+	if e.seg <= arcT2 {
+		a.n--
+		if cb {
+			a.notify(e, r)
+		}
+	}
+	a.pool.put(e)
+}
+
+func (a *Arc[K, V]) notify(e *lru_element[K, V], r LruEvictReason) {
+	if a.cback != nil {
+		a.cback(e.v.key, e.v.val)
+	}
+	if a.ecback != nil {
+		a.ecback(e.v.key, e.v.val, e.v.size, r)
+	}
+}
@@ -0,0 +1,132 @@
+package container
+
+import (
+	"testing"
+	"time"
+)
+
+var _ LruCache[int, int] = (*Arc[int, int])(nil)
+
+func TestArcSimple(t *testing.T) {
+	var deleted []int
+	a := NewArc(4, time.Hour, func(k, v int) {
+		deleted = append(deleted, k)
+	})
+	a.Put(1, 1, 1)
+	a.Put(2, 2, 1)
+	a.Put(3, 3, 2)
+	if a.Len() != 3 || a.Size() != 4 || a.Get(1) == nil {
+		t.Fatal("expecting 3 elements, but len=", a.Len(), " size=", a.Size())
+	}
+	if a.sizes[arcT1] != 3 || a.sizes[arcT2] != 1 {
+		t.Fatal("1 must be moved to T2")
+	}
+
+	// T1 is bigger than the target, so 2 goes to B1
+	a.Put(4, 4, 1)
+	if len(deleted) != 1 || deleted[0] != 2 || a.Peek(2) != nil || a.kvMap[2].seg != arcB1 {
+		t.Fatal("2 must be evicted to B1, deleted=", deleted)
+	}
+
+	// the ghost hit increases the target size of T1
+	a.Put(2, 2, 1)
+	if a.Target() != 1 || a.Peek(2) == nil || a.kvMap[2].seg != arcT2 {
+		t.Fatal("expecting target=1 and 2 in T2, but target=", a.Target())
+	}
+	// 3 is evicted to free space for 2
+	if a.Len() != 3 || a.Size() != 3 || a.kvMap[3].seg != arcB1 {
+		t.Fatal("wrong len=", a.Len(), " or size=", a.Size())
+	}
+}
+
+func TestArcGhostB2(t *testing.T) {
+	a := NewArc[int, int](2, 0, nil)
+	a.Put(1, 1, 1)
+	a.Put(2, 2, 1)
+	a.Get(1)
+	a.Get(2)
+	a.Put(3, 3, 1) // 1 goes to B2
+	if a.kvMap[1].seg != arcB2 {
+		t.Fatal("1 must be in B2")
+	}
+	a.p = 1
+	a.Put(1, 1, 1)
+	if a.Target() != 0 || a.kvMap[1].seg != arcT2 {
+		t.Fatal("the target must decrease, but it is ", a.Target())
+	}
+}
+
+func TestArcGhostsBounded(t *testing.T) {
+	a := NewArc[int, int](10, 0, nil)
+	for i := 0; i < 1000; i++ {
+		a.Put(i, i, 1)
+		if i%3 == 0 {
+			a.Get(i)
+		}
+		a.Put(i/2, i, 1)
+	}
+	if a.Size() > 10 || len(a.kvMap) > 20 {
+		t.Fatal("size=", a.Size(), " total keys=", len(a.kvMap))
+	}
+	total := int64(0)
+	for _, sz := range a.sizes {
+		total += sz
+	}
+	if total > 20 || a.sizes[arcT1]+a.sizes[arcB1] > 10 {
+		t.Fatal("wrong lists sizes ", a.sizes)
+	}
+
+	n := 0
+	a.Iterate(func(k, v int) bool {
+		n++
+		return true
+	})
+	if n != a.Len() {
+		t.Fatal("expecting ", a.Len(), " visited, but ", n)
+	}
+
+	a.Clear(true)
+	if a.Len() != 0 || len(a.kvMap) != 0 || a.Target() != 0 {
+		t.Fatal("must be empty")
+	}
+}
+
+func TestArcTimeout(t *testing.T) {
+	now := time.Now()
+	deleted := 0
+	a := NewArcWithClock(10, time.Second, func(k, v int) {
+		deleted++
+	}, func() time.Time { return now })
+	a.Put(1, 1, 1)
+	a.Put(2, 2, 1)
+	a.Get(2)
+	now = now.Add(2 * time.Second)
+	a.Put(3, 3, 1)
+	if a.Len() != 1 || deleted != 2 || len(a.kvMap) != 1 {
+		t.Fatal("1 and 2 must be expired")
+	}
+}
+
+func BenchmarkSkewedArc(b *testing.B) {
+	trace := skewedTrace(1000000)
+	b.ResetTimer()
+	runSkewedTrace(b, NewArc[int, int](50000, 0, nil), trace)
+}
+
+func TestArcPool(t *testing.T) {
+	a := NewArc[int, int](10, time.Hour, nil)
+	a.SetPoolSize(3)
+	for i := 0; i < 5; i++ {
+		a.Put(i, i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		a.Delete(i)
+	}
+	if a.pool.len != 3 {
+		t.Fatal("expecting 3 elements in the pool, but ", a.pool.len)
+	}
+	a.Put(1, 1, 1)
+	if a.pool.len != 2 || a.Peek(1).Val() != 1 {
+		t.Fatal("expecting 2 elements in the pool, but ", a.pool.len)
+	}
+}
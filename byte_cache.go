@@ -0,0 +1,220 @@
+package container
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/maphash"
+
+	"github.com/kplr-io/container/btsbuf"
+)
+
+type (
+	// ByteCache is the cache of slices of bytes, which is friendly to the
+	// garbage collector. The values are stored in big preallocated arenas as
+	// btsbuf chunks, and the index keeps only integer hashes of the keys and
+	// the chunks offsets, so millions of elements don't add pointers to scan.
+	//
+	// The values are written to the current arena until it is full, then the
+	// cache switches to a free arena. If there is no free arena, the least
+	// recently used one is evicted with all its elements. The arenas order is
+	// kept by Lru, an arena is touched when an element is written to it or read
+	// from it.
+	//
+	// ByteCache is not thread-safe.
+	ByteCache struct {
+		seed      maphash.Seed
+		index     map[uint64]uint64
+		arenas    *TypedLru[uint32, *byte_arena]
+		cur       *byte_arena
+		free      []*byte_arena
+		arenaSize int
+		maxArenas int
+		nextId    uint32
+	}
+
+	byte_arena struct {
+		id   uint32
+		w    btsbuf.Writer
+		buf  []byte
+		offs int
+	}
+)
+
+// byteCacheKeyLen is the size of the key length field in the chunk
+const byteCacheKeyLen = 2
+
+var (
+	errByteCacheTooBig = errors.New("the element is too big for the arena")
+	errByteCacheKey    = errors.New("the key is too long")
+)
+
+// NewByteCache creates new ByteCache with up to maxArenas arenas, every arena
+// is arenaSize bytes. The arenas are allocated when they are needed first time.
+func NewByteCache(arenaSize, maxArenas int) *ByteCache {
+	if arenaSize < 8 || maxArenas < 1 {
+		panic("arena size and number of arenas must be positive")
+	}
+	bc := new(ByteCache)
+	bc.seed = maphash.MakeSeed()
+	bc.index = make(map[uint64]uint64)
+	bc.arenaSize = arenaSize
+	bc.maxArenas = maxArenas
+	bc.arenas = NewTypedLru(int64(maxArenas), 0, bc.onArenaEvict)
+	return bc
+}
+
+// Put places the copy of val into the cache. It returns an error if the
+// key and the value cannot fit into one arena.
+func (bc *ByteCache) Put(key string, val []byte) error {
+	if len(key) > 0xFFFF {
+		return errByteCacheKey
+	}
+	ln := byteCacheKeyLen + len(key) + len(val)
+	if ln+4 > bc.arenaSize {
+		return errByteCacheTooBig
+	}
+
+	if bc.cur == nil {
+		bc.switchArena()
+	}
+	chunk, err := bc.cur.w.Allocate(ln, false)
+	if err != nil {
+		bc.switchArena()
+		chunk, _ = bc.cur.w.Allocate(ln, false)
+	}
+	offs := bc.cur.offs
+	bc.cur.offs += ln + 4
+	binary.BigEndian.PutUint16(chunk, uint16(len(key)))
+	copy(chunk[byteCacheKeyLen:], key)
+	copy(chunk[byteCacheKeyLen+len(key):], val)
+
+	bc.index[bc.hash(key)] = uint64(bc.cur.id)<<32 | uint64(offs)
+	bc.arenas.Get(bc.cur.id)
+	return nil
+}
+
+// Get returns copy of the value for the key, or nil if there is no such key
+func (bc *ByteCache) Get(key string) []byte {
+	v := bc.View(key)
+	if v == nil {
+		return nil
+	}
+	res := make([]byte, len(v))
+	copy(res, v)
+	return res
+}
+
+// View returns the value for the key without copying, or nil if there is no
+// such key. The returned slice must not be modified, and it is valid until the
+// next Put or Clear call.
+func (bc *ByteCache) View(key string) []byte {
+	h := bc.hash(key)
+	pos, ok := bc.index[h]
+	if !ok {
+		return nil
+	}
+	a := bc.arenas.Get(uint32(pos >> 32))
+	if a == nil {
+		return nil
+	}
+	k, v := a.Val().chunk(int(pos & 0xFFFFFFFF))
+	if string(k) != key {
+		return nil
+	}
+	return v
+}
+
+// Delete removes the key from the cache. The space the element occupies is
+// released when its arena is evicted.
+func (bc *ByteCache) Delete(key string) {
+	h := bc.hash(key)
+	pos, ok := bc.index[h]
+	if !ok {
+		return
+	}
+	if a := bc.arenas.Peek(uint32(pos >> 32)); a != nil {
+		if k, _ := a.Val().chunk(int(pos & 0xFFFFFFFF)); string(k) == key {
+			delete(bc.index, h)
+		}
+	}
+}
+
+// Len returns number of elements in the cache
+func (bc *ByteCache) Len() int {
+	return len(bc.index)
+}
+
+// Arenas returns number of the arenas in use
+func (bc *ByteCache) Arenas() int {
+	return bc.arenas.Len()
+}
+
+// Clear drops all the elements, the arenas are kept for reuse
+func (bc *ByteCache) Clear() {
+	bc.arenas.Clear(true)
+	bc.cur = nil
+}
+
+func (bc *ByteCache) hash(key string) uint64 {
+	return maphash.String(bc.seed, key)
+}
+
+// switchArena closes the current arena and makes a free one current. The
+// least recently used arena is evicted if there is no free arena and the
+// maximum number of arenas is reached.
+func (bc *ByteCache) switchArena() {
+	if bc.cur != nil {
+		bc.cur.w.Close()
+		bc.cur = nil
+	}
+
+	if bc.arenas.Len() >= bc.maxArenas {
+		c := bc.arenas.ReverseCursor()
+		c.Next()
+		c.Delete()
+	}
+
+	var a *byte_arena
+	if n := len(bc.free); n > 0 {
+		a = bc.free[n-1]
+		bc.free = bc.free[:n-1]
+	} else {
+		a = &byte_arena{buf: make([]byte, bc.arenaSize)}
+	}
+	a.id = bc.nextId
+	bc.nextId++
+	a.offs = 0
+	a.w.Reset(a.buf, false)
+	bc.arenas.Put(a.id, a, 1)
+	bc.cur = a
+}
+
+// onArenaEvict drops the index entries pointing to the arena and puts the
+// arena to the free list
+func (bc *ByteCache) onArenaEvict(id uint32, a *byte_arena) {
+	var rd btsbuf.Reader
+	if rd.Reset(a.buf[:a.offs]) == nil {
+		offs := 0
+		for ; !rd.End(); rd.Next() {
+			chunk := rd.Get()
+			kl := int(binary.BigEndian.Uint16(chunk))
+			h := maphash.Bytes(bc.seed, chunk[byteCacheKeyLen:byteCacheKeyLen+kl])
+			if bc.index[h] == uint64(id)<<32|uint64(offs) {
+				delete(bc.index, h)
+			}
+			offs += len(chunk) + 4
+		}
+	}
+	if bc.cur == a {
+		bc.cur = nil
+	}
+	bc.free = append(bc.free, a)
+}
+
+// chunk returns the key and the value stored in the chunk at offs
+func (a *byte_arena) chunk(offs int) ([]byte, []byte) {
+	ln := int(binary.BigEndian.Uint32(a.buf[offs:]))
+	chunk := a.buf[offs+4 : offs+4+ln]
+	kl := int(binary.BigEndian.Uint16(chunk))
+	return chunk[byteCacheKeyLen : byteCacheKeyLen+kl], chunk[byteCacheKeyLen+kl:]
+}
@@ -0,0 +1,107 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestByteCacheSimple(t *testing.T) {
+	bc := NewByteCache(1024, 4)
+	if bc.Get("a") != nil || bc.Arenas() != 0 {
+		t.Fatal("must be empty")
+	}
+	bc.Put("a", []byte("hello"))
+	bc.Put("b", []byte("world"))
+	bc.Put("e", nil)
+	if string(bc.Get("a")) != "hello" || string(bc.View("b")) != "world" || bc.Len() != 3 {
+		t.Fatal("wrong values")
+	}
+	if v := bc.Get("e"); v == nil || len(v) != 0 {
+		t.Fatal("expecting empty value, but got ", v)
+	}
+
+	bc.Put("a", []byte("hi"))
+	if string(bc.Get("a")) != "hi" || bc.Len() != 3 {
+		t.Fatal("a must be replaced")
+	}
+
+	bc.Delete("a")
+	if bc.Get("a") != nil || bc.Len() != 2 {
+		t.Fatal("a must be deleted")
+	}
+
+	bc.Clear()
+	if bc.Get("b") != nil || bc.Len() != 0 || bc.Arenas() != 0 || len(bc.free) != 1 {
+		t.Fatal("must be empty")
+	}
+	bc.Put("c", []byte("c"))
+	if string(bc.Get("c")) != "c" || len(bc.free) != 0 {
+		t.Fatal("the arena must be reused")
+	}
+}
+
+func TestByteCacheErrors(t *testing.T) {
+	bc := NewByteCache(64, 2)
+	if bc.Put("a", make([]byte, 60)) == nil {
+		t.Fatal("expecting too big error")
+	}
+	if bc.Put(strings.Repeat("a", 0x10000), nil) == nil {
+		t.Fatal("expecting too long key error")
+	}
+	if bc.Put("a", make([]byte, 57)) != nil {
+		t.Fatal("must fit exactly")
+	}
+}
+
+func TestByteCacheEviction(t *testing.T) {
+	// every element is 4+2+3+11 = 20 bytes, so 5 elements per arena
+	bc := NewByteCache(100, 3)
+	for i := 0; i < 15; i++ {
+		bc.Put(fmt.Sprintf("k%02d", i), []byte(fmt.Sprintf("value %04d", i)))
+	}
+	if bc.Len() != 15 || bc.Arenas() != 3 {
+		t.Fatal("expecting 15 elements in 3 arenas, but ", bc.Len(), " in ", bc.Arenas())
+	}
+
+	// touch the first arena, so the second one is the least recently used
+	if string(bc.Get("k01")) != "value 0001" {
+		t.Fatal("wrong value for k01")
+	}
+	bc.Put("k15", []byte("value 0015"))
+	if bc.Len() != 11 || bc.Arenas() != 3 {
+		t.Fatal("expecting 11 elements in 3 arenas, but ", bc.Len(), " in ", bc.Arenas())
+	}
+	for i := 0; i < 16; i++ {
+		v := bc.Get(fmt.Sprintf("k%02d", i))
+		if (i >= 5 && i < 10) != (v == nil) {
+			t.Fatal("only the second arena must be evicted, but k", i, "=", string(v))
+		}
+	}
+
+	// the replaced element is not dropped with the old arena
+	bc.Put("k01", []byte("value 1001"))
+	for i := 0; i < 10; i++ {
+		bc.Put(fmt.Sprintf("n%02d", i), []byte("value"))
+	}
+	if string(bc.Get("k01")) != "value 1001" {
+		t.Fatal("wrong value for k01 ", string(bc.Get("k01")))
+	}
+}
+
+func BenchmarkByteCache(b *testing.B) {
+	bc := NewByteCache(1<<20, 16)
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	val := make([]byte, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i%len(keys)]
+		if bc.View(k) == nil {
+			bc.Put(k, val)
+		}
+	}
+}
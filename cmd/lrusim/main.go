@@ -0,0 +1,94 @@
+// lrusim replays the access trace through the caches of the container
+// package with different sizes and prints the hit ratios and the evictions
+// counts, so the miss-ratio curves can be plotted.
+//
+// The trace file contains one access per line: the key, the size and the
+// unix timestamp in seconds separated by spaces. The trace time is used as
+// the caches clock, so the simulation is not bound to the real time.
+//
+// Usage:
+//
+//	lrusim -min 1M -max 1G -steps 10 -ttl 1h -policies lru,arc trace.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "lrusim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, out io.Writer) error {
+	var names []string
+	for n := range policies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fs := flag.NewFlagSet("lrusim", flag.ContinueOnError)
+	minSize := fs.String("min", "1M", "the smallest cache size, K, M and G suffixes are allowed")
+	maxSize := fs.String("max", "1G", "the biggest cache size, K, M and G suffixes are allowed")
+	steps := fs.Int("steps", 10, "number of the cache sizes between min and max")
+	ttl := fs.Duration("ttl", 0, "the maximum time an element can stay in the cache untouched, 0 means forever")
+	pols := fs.String("policies", strings.Join(names, ","), "comma separated list of the policies to simulate")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lrusim [flags] [trace file]\n\nThe trace is read from stdin if the file is not provided.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	mn, err := parseSize(*minSize)
+	if err != nil {
+		return err
+	}
+	mx, err := parseSize(*maxSize)
+	if err != nil {
+		return err
+	}
+
+	var sel []string
+	for _, p := range strings.Split(*pols, ",") {
+		p = strings.TrimSpace(p)
+		if _, ok := policies[p]; !ok {
+			return fmt.Errorf("unknown policy %q, known ones are %s", p, strings.Join(names, ","))
+		}
+		sel = append(sel, p)
+	}
+
+	r := stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	tr, err := readTrace(r)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "policy\tsize\thit ratio\tbyte hit ratio\tevicted by size\tevicted by time\trejected")
+	for _, p := range sel {
+		for _, sz := range sizesSweep(mn, mx, *steps) {
+			res := simulate(tr, p, policies[p], sz, *ttl)
+			fmt.Fprintf(tw, "%s\t%d\t%.4f\t%.4f\t%d\t%d\t%d\n", res.policy, res.maxSize, res.HitRatio(),
+				res.ByteHitRatio(), res.evictedBySize, res.evictedByTime, res.rejected)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kplr-io/container"
+)
+
+type (
+	// trace is the parsed trace file
+	trace struct {
+		reqs []request
+		// keys is the number of distinct keys in the trace
+		keys int
+	}
+
+	// request is one access of the trace
+	request struct {
+		key  string
+		size int64
+		ts   time.Time
+	}
+
+	// result is the outcome of the trace replay through one policy with one
+	// cache size
+	result struct {
+		policy        string
+		maxSize       int64
+		requests      int64
+		hits          int64
+		bytes         int64
+		hitBytes      int64
+		evictedBySize int64
+		evictedByTime int64
+		rejected      int64
+	}
+
+	// sim_cache is the cache under simulation. put returns false if the
+	// element was not admitted into the cache.
+	sim_cache struct {
+		cache container.LruCache[string, struct{}]
+		put   func(k string, size int64) bool
+	}
+
+	// newCacheF creates the cache with maximum size maxSize for the trace with
+	// keys distinct keys, the cache must use clck for discovering current time
+	// and must report the evictions to ecb
+	newCacheF func(maxSize int64, to time.Duration, keys int, clck container.TsClockNowF, ecb container.LruEvictCallback[string, struct{}]) sim_cache
+)
+
+// policies contains the constructors of the caches, which can be simulated
+var policies = map[string]newCacheF{
+	"lru": func(maxSize int64, to time.Duration, keys int, clck container.TsClockNowF, ecb container.LruEvictCallback[string, struct{}]) sim_cache {
+		l := container.NewLruWithClock[string, struct{}](maxSize, to, nil, clck)
+		l.SetEvictCallback(ecb)
+		return simCache(l)
+	},
+	"slru": func(maxSize int64, to time.Duration, keys int, clck container.TsClockNowF, ecb container.LruEvictCallback[string, struct{}]) sim_cache {
+		s := container.NewSlruWithClock[string, struct{}](maxSize, maxSize*8/10, to, nil, clck)
+		s.SetEvictCallback(ecb)
+		return simCache(s)
+	},
+	"arc": func(maxSize int64, to time.Duration, keys int, clck container.TsClockNowF, ecb container.LruEvictCallback[string, struct{}]) sim_cache {
+		a := container.NewArcWithClock[string, struct{}](maxSize, to, nil, clck)
+		a.SetEvictCallback(ecb)
+		return simCache(a)
+	},
+	"tinylfu": func(maxSize int64, to time.Duration, keys int, clck container.TsClockNowF, ecb container.LruEvictCallback[string, struct{}]) sim_cache {
+		l := container.NewLruWithClock[string, struct{}](maxSize, to, nil, clck)
+		l.SetEvictCallback(ecb)
+		tl := container.NewTinyLfu(l, keys)
+		return sim_cache{cache: tl, put: func(k string, size int64) bool {
+			return tl.TryPut(k, struct{}{}, size)
+		}}
+	},
+}
+
+func simCache(c container.LruCache[string, struct{}]) sim_cache {
+	return sim_cache{cache: c, put: func(k string, size int64) bool {
+		c.Put(k, struct{}{}, size)
+		return true
+	}}
+}
+
+// readTrace reads the trace, every line of which contains the key, the size
+// and the unix timestamp in seconds (may be fractional) separated by spaces.
+// Empty lines and the lines started with # are skipped.
+func readTrace(r io.Reader) (trace, error) {
+	var tr trace
+	keys := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fs := strings.Fields(line)
+		if len(fs) != 3 {
+			return tr, fmt.Errorf("line %d: expecting key, size and timestamp, but got %q", ln, line)
+		}
+		size, err := strconv.ParseInt(fs[1], 10, 64)
+		if err != nil || size < 0 {
+			return tr, fmt.Errorf("line %d: invalid size %q", ln, fs[1])
+		}
+		sec, err := strconv.ParseFloat(fs[2], 64)
+		if err != nil {
+			return tr, fmt.Errorf("line %d: invalid timestamp %q", ln, fs[2])
+		}
+		// the keys are interned, the traces repeat them a lot
+		k, ok := keys[fs[0]]
+		if !ok {
+			k = strings.Clone(fs[0])
+			keys[k] = k
+		}
+		whole, frac := math.Modf(sec)
+		tr.reqs = append(tr.reqs, request{key: k, size: size, ts: time.Unix(int64(whole), int64(frac*1e9))})
+	}
+	tr.keys = len(keys)
+	return tr, sc.Err()
+}
+
+// simulate replays the trace through the cache created by newCache. Every
+// missed key is put into the cache, unless it is bigger than the cache.
+func simulate(tr trace, policy string, newCache newCacheF, maxSize int64, to time.Duration) result {
+	res := result{policy: policy, maxSize: maxSize}
+	var now time.Time
+	clck := func() time.Time { return now }
+	c := newCache(maxSize, to, tr.keys, clck, func(k string, v struct{}, size int64, r container.LruEvictReason) {
+		switch r {
+		case container.LruCapacity:
+			res.evictedBySize++
+		case container.LruExpired:
+			res.evictedByTime++
+		}
+	})
+
+	for _, rq := range tr.reqs {
+		// the trace may be slightly out of order, the clock never goes back
+		if rq.ts.After(now) {
+			now = rq.ts
+		}
+		res.requests++
+		res.bytes += rq.size
+		if c.cache.Get(rq.key) != nil {
+			res.hits++
+			res.hitBytes += rq.size
+			continue
+		}
+		if rq.size > maxSize || !c.put(rq.key, rq.size) {
+			res.rejected++
+		}
+	}
+	return res
+}
+
+// HitRatio returns the part of the requests which hit the cache
+func (r result) HitRatio() float64 {
+	return ratio(r.hits, r.requests)
+}
+
+// ByteHitRatio returns the part of the requested bytes which hit the cache
+func (r result) ByteHitRatio() float64 {
+	return ratio(r.hitBytes, r.bytes)
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// parseSize parses the size with optional K, M or G suffix (powers of 1024)
+func parseSize(s string) (int64, error) {
+	mul := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mul = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mul = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mul = 1 << 30
+	}
+	if mul > 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return v * mul, nil
+}
+
+// sizesSweep returns steps sizes from lo to hi growing geometrically
+func sizesSweep(lo, hi int64, steps int) []int64 {
+	if steps < 2 || lo >= hi {
+		return []int64{hi}
+	}
+	res := make([]int64, 0, steps)
+	k := math.Pow(float64(hi)/float64(lo), 1/float64(steps-1))
+	for i := 0; i < steps; i++ {
+		sz := int64(math.Round(float64(lo) * math.Pow(k, float64(i))))
+		if len(res) > 0 && sz <= res[len(res)-1] {
+			continue
+		}
+		res = append(res, sz)
+	}
+	res[len(res)-1] = hi
+	return res
+}
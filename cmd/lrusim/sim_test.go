@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTrace = `# key size ts
+a 10 100
+b 10 101
+a 10 102
+c 20 103.5
+
+a 10 104
+b 10 200
+`
+
+func TestReadTrace(t *testing.T) {
+	tr, err := readTrace(strings.NewReader(testTrace))
+	if err != nil || len(tr.reqs) != 6 || tr.keys != 3 {
+		t.Fatal("unexpected trace ", tr, err)
+	}
+	if rq := tr.reqs[3]; rq.key != "c" || rq.size != 20 || !rq.ts.Equal(time.Unix(103, 5e8)) {
+		t.Fatal("unexpected request ", rq)
+	}
+
+	if _, err := readTrace(strings.NewReader("a 10")); err == nil {
+		t.Fatal("expecting error for missing timestamp")
+	}
+	if _, err := readTrace(strings.NewReader("a -1 100")); err == nil {
+		t.Fatal("expecting error for negative size")
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	tr, _ := readTrace(strings.NewReader(testTrace))
+
+	// c evicts b, a is a hit twice, the second b is a miss and evicts c
+	res := simulate(tr, "lru", policies["lru"], 30, 0)
+	if res.requests != 6 || res.hits != 2 || res.hitBytes != 20 || res.bytes != 70 || res.evictedBySize != 2 {
+		t.Fatal("unexpected result ", res)
+	}
+	if res.HitRatio() != 2.0/6 || res.ByteHitRatio() != 2.0/7 {
+		t.Fatal("unexpected ratios ", res.HitRatio(), res.ByteHitRatio())
+	}
+
+	// b expires by the time it is requested again
+	res = simulate(tr, "lru", policies["lru"], 100, time.Minute)
+	if res.hits != 2 || res.evictedByTime != 3 || res.evictedBySize != 0 {
+		t.Fatal("unexpected result ", res)
+	}
+
+	// only one element fits into the cache, c doesn't fit at all
+	res = simulate(tr, "lru", policies["lru"], 15, 0)
+	if res.hits != 1 || res.rejected != 1 || res.evictedBySize != 3 {
+		t.Fatal("unexpected result ", res)
+	}
+
+	for p, nc := range policies {
+		res = simulate(tr, p, nc, 100, 0)
+		if res.hits != 3 || res.evictedBySize != 0 {
+			t.Fatal("unexpected result for ", p, ": ", res)
+		}
+	}
+}
+
+func TestSizesSweep(t *testing.T) {
+	szs := sizesSweep(1, 1000, 4)
+	if len(szs) != 4 || szs[0] != 1 || szs[1] != 10 || szs[2] != 100 || szs[3] != 1000 {
+		t.Fatal("unexpected sizes ", szs)
+	}
+	if szs = sizesSweep(1, 3, 10); len(szs) != 3 {
+		t.Fatal("expecting distinct sizes only ", szs)
+	}
+	if sz, err := parseSize("2M"); err != nil || sz != 2<<20 {
+		t.Fatal("unexpected size ", sz, err)
+	}
+	if _, err := parseSize("xK"); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-min", "10", "-max", "100", "-steps", "2", "-policies", "lru,arc"}, strings.NewReader(testTrace), &out)
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 5 {
+		t.Fatal("expecting header and 4 lines, but ", out.String())
+	}
+	if run([]string{"-policies", "fifo"}, strings.NewReader(testTrace), &out) == nil {
+		t.Fatal("expecting error for unknown policy")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/kplr-io/container
+
+go 1.24
@@ -0,0 +1,259 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// LoadingLru is the concurrency-safe wrapper over Lru, which loads missing
+	// values by a loader function. Concurrent loads of the same key are
+	// deduplicated, so only one loader call for the key is in progress at a
+	// time and other callers wait for its result. The loader errors can be
+	// cached for some time (negative TTL), so failing keys don't hammer the
+	// backend.
+	//
+	// In the stale-while-revalidate mode (see SetRevalidate) the values older
+	// than soft TTL are still returned, but they are reloaded in background.
+	LoadingLru[K comparable, V any] struct {
+		lock    sync.Mutex
+		lru     *TypedLru[K, V]
+		errs    *TypedLru[K, error]
+		errTTL  time.Duration
+		calls   map[K]*lru_load_call[V]
+		softTTL time.Duration
+		hardTTL time.Duration
+	}
+
+	// LruLoader loads the value for the key k. It returns the value, its size
+	// which is used for placing the value into the cache, or an error.
+	LruLoader[K comparable, V any] func(ctx context.Context, k K) (V, int64, error)
+
+	lru_load_call[V any] struct {
+		done chan struct{}
+		val  V
+		err  error
+		// superseded is true if the key was put or deleted while the load
+		// was in progress, so the load result must not be stored
+		superseded bool
+	}
+)
+
+// NewLoadingLru creates new LoadingLru on top of the l. The l must not be used
+// directly after that. errTTL is the time a loader error is kept for the key,
+// up to maxErrs errors are kept. errTTL 0 means the errors are not cached.
+//
+// The l delete callback is invoked under the LoadingLru lock, so it must not
+// call the LoadingLru methods.
+func NewLoadingLru[K comparable, V any](l *TypedLru[K, V], errTTL time.Duration, maxErrs int) *LoadingLru[K, V] {
+	ll := new(LoadingLru[K, V])
+	ll.lru = l
+	ll.errTTL = errTTL
+	if errTTL > 0 {
+		ll.errs = NewLruWithClock[K, error](int64(maxErrs), 0, nil, l.clockNow)
+	}
+	ll.calls = make(map[K]*lru_load_call[V])
+	return ll
+}
+
+// GetOrLoad returns the value for the key k. If the value is not in the cache,
+// it is loaded by loader and placed into the cache with the size the loader
+// returned. If there is a load for k in progress already, GetOrLoad waits its
+// result. If the loader error for k is cached, the error is returned without
+// calling loader.
+//
+// The loader is called in a separate goroutine with the ctx values, but it is
+// not canceled together with the ctx, because other callers may wait the same
+// load. GetOrLoad stops waiting and returns ctx.Err() when the ctx is done. If
+// the loader panics, the panic is returned as an error.
+func (ll *LoadingLru[K, V]) GetOrLoad(ctx context.Context, k K, loader LruLoader[K, V]) (V, error) {
+	v, _, err := ll.GetOrLoadStale(ctx, k, loader)
+	return v, err
+}
+
+// SetRevalidate turns on the stale-while-revalidate mode. The loaded values
+// are kept in the cache for hardTTL since they are loaded. When a value is
+// older than softTTL, GetOrLoadStale returns it as stale and reloads it in
+// background, so the callers don't wait the loader. Only one reload per key is
+// in progress at a time, and a failed reload doesn't affect the stale value.
+// GetStale reports staleness too, but doesn't reload the value.
+// softTTL 0 turns the mode off.
+func (ll *LoadingLru[K, V]) SetRevalidate(softTTL, hardTTL time.Duration) {
+	if softTTL > hardTTL {
+		panic(fmt.Sprint("soft TTL=", softTTL, " must not be bigger than hard TTL=", hardTTL))
+	}
+	ll.lock.Lock()
+	ll.softTTL = softTTL
+	ll.hardTTL = hardTTL
+	if softTTL == 0 {
+		ll.hardTTL = 0
+	}
+	ll.lock.Unlock()
+}
+
+// GetOrLoadStale same as GetOrLoad, but it also reports whether the returned
+// value is stale, see SetRevalidate. The background reload is started with
+// the ctx values, but it is not canceled together with the ctx.
+func (ll *LoadingLru[K, V]) GetOrLoadStale(ctx context.Context, k K, loader LruLoader[K, V]) (V, bool, error) {
+	ll.lock.Lock()
+	if v := ll.lru.Get(k); v != nil {
+		res := v.Val()
+		stale := ll.isStale(v)
+		if stale {
+			ll.revalidate(ctx, k, loader)
+		}
+		ll.lock.Unlock()
+		return res, stale, nil
+	}
+
+	if ll.errs != nil {
+		if e := ll.errs.Get(k); e != nil {
+			err := e.Val()
+			ll.lock.Unlock()
+			var zv V
+			return zv, false, err
+		}
+	}
+
+	c, ok := ll.calls[k]
+	if !ok {
+		c = &lru_load_call[V]{done: make(chan struct{})}
+		ll.calls[k] = c
+	}
+	ll.lock.Unlock()
+
+	if !ok {
+		// the load is shared by all the callers, so it must not be canceled
+		// together with the ctx of the first one
+		go ll.load(context.WithoutCancel(ctx), k, c, loader)
+	}
+
+	select {
+	case <-c.done:
+		return c.val, false, c.err
+	case <-ctx.Done():
+		var zv V
+		return zv, false, ctx.Err()
+	}
+}
+
+// Get returns the cached value for the key k, the second returned value
+// is false, if there is no such key in the cache. In the stale-while-revalidate
+// mode the stale values are returned as well, see GetStale.
+func (ll *LoadingLru[K, V]) Get(k K) (V, bool) {
+	v, _, ok := ll.GetStale(k)
+	return v, ok
+}
+
+// GetStale same as Get, but it also reports whether the returned value is
+// stale, see SetRevalidate. GetStale doesn't know the loader, so it doesn't
+// reload the stale value, use GetOrLoadStale for that.
+func (ll *LoadingLru[K, V]) GetStale(k K) (V, bool, bool) {
+	ll.lock.Lock()
+	defer ll.lock.Unlock()
+	if v := ll.lru.Get(k); v != nil {
+		return v.Val(), ll.isStale(v), true
+	}
+	var zv V
+	return zv, false, false
+}
+
+// Put places the value into the cache. It also drops the cached error for k,
+// and the result of the load of k in progress is not stored then.
+// In the stale-while-revalidate mode the value is kept for hard TTL.
+func (ll *LoadingLru[K, V]) Put(k K, v V, size int64) {
+	ll.lock.Lock()
+	ll.supersede(k)
+	if ll.errs != nil {
+		ll.errs.Delete(k)
+	}
+	ll.lru.PutWithTTL(k, v, size, ll.hardTTL)
+	ll.lock.Unlock()
+}
+
+// Delete drops the value and the cached error for k. The result of the load
+// of k in progress is not stored then.
+func (ll *LoadingLru[K, V]) Delete(k K) {
+	ll.lock.Lock()
+	ll.supersede(k)
+	if ll.errs != nil {
+		ll.errs.Delete(k)
+	}
+	ll.lru.Delete(k)
+	ll.lock.Unlock()
+}
+
+func (ll *LoadingLru[K, V]) Len() int {
+	ll.lock.Lock()
+	defer ll.lock.Unlock()
+	return ll.lru.Len()
+}
+
+// isStale returns whether the value is older than soft TTL, must be called
+// under the lock
+func (ll *LoadingLru[K, V]) isStale(v *TypedLruValue[K, V]) bool {
+	if ll.softTTL == 0 || v.ExpiresAt().IsZero() {
+		return false
+	}
+	softExp := v.ExpiresAt().Add(ll.softTTL - ll.hardTTL)
+	return ll.lru.clockNow().After(softExp)
+}
+
+// revalidate starts the background reload of the key k, if there is no load
+// in progress and there is no cached error for k. Must be called under the lock
+func (ll *LoadingLru[K, V]) revalidate(ctx context.Context, k K, loader LruLoader[K, V]) {
+	if _, ok := ll.calls[k]; ok {
+		return
+	}
+	if ll.errs != nil && ll.errs.Peek(k) != nil {
+		return
+	}
+	c := &lru_load_call[V]{done: make(chan struct{})}
+	ll.calls[k] = c
+	go ll.load(context.WithoutCancel(ctx), k, c, loader)
+}
+
+// load calls the loader and completes the call c. If the loader panics, the
+// panic is turned into the call error.
+func (ll *LoadingLru[K, V]) load(ctx context.Context, k K, c *lru_load_call[V], loader LruLoader[K, V]) {
+	var size int64
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("the loader panicked: %v", r)
+		}
+		ll.complete(k, c, size)
+	}()
+	c.val, size, c.err = loader(ctx, k)
+}
+
+// supersede detaches the load of k in progress, if any, so its result is not
+// stored. The waiters of the load still get the result. Must be called under
+// the lock
+func (ll *LoadingLru[K, V]) supersede(k K) {
+	if c, ok := ll.calls[k]; ok {
+		c.superseded = true
+		delete(ll.calls, k)
+	}
+}
+
+// complete stores the load result, unless the load is superseded, and
+// notifies the waiters
+func (ll *LoadingLru[K, V]) complete(k K, c *lru_load_call[V], size int64) {
+	ll.lock.Lock()
+	if c.superseded {
+		ll.lock.Unlock()
+		close(c.done)
+		return
+	}
+	delete(ll.calls, k)
+	if c.err == nil {
+		ll.lru.PutWithTTL(k, c.val, size, ll.hardTTL)
+	} else if ll.errs != nil && !errors.Is(c.err, context.Canceled) && !errors.Is(c.err, context.DeadlineExceeded) {
+		ll.errs.PutWithTTL(k, c.err, 1, ll.errTTL)
+	}
+	ll.lock.Unlock()
+	close(c.done)
+}
@@ -0,0 +1,315 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingLruSingleFlight(t *testing.T) {
+	ll := NewLoadingLru(NewTypedLru[string, int](100, time.Hour, nil), 0, 0)
+	var calls int32
+	start := make(chan struct{})
+	loader := func(ctx context.Context, k string) (int, int64, error) {
+		atomic.AddInt32(&calls, 1)
+		<-start
+		return len(k), 10, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := ll.GetOrLoad(context.Background(), "abc", loader)
+			if err != nil || v != 3 {
+				t.Error("expecting 3, but got ", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(start)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatal("expecting 1 loader call, but got ", calls)
+	}
+	if v, ok := ll.Get("abc"); !ok || v != 3 || ll.lru.Size() != 10 {
+		t.Fatal("the value must be cached with size 10")
+	}
+}
+
+func TestLoadingLruErrors(t *testing.T) {
+	now := time.Now()
+	l := NewLruWithClock[int, int](100, 0, nil, func() time.Time { return now })
+	ll := NewLoadingLru(l, time.Second, 10)
+	calls := 0
+	errLoad := errors.New("load failed")
+	loader := func(ctx context.Context, k int) (int, int64, error) {
+		calls++
+		if calls == 1 {
+			return 0, 0, errLoad
+		}
+		return k, 1, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := ll.GetOrLoad(context.Background(), 1, loader); err != errLoad {
+			t.Fatal("expecting errLoad, but got ", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatal("the error must be cached, but calls=", calls)
+	}
+
+	now = now.Add(2 * time.Second)
+	if v, err := ll.GetOrLoad(context.Background(), 1, loader); err != nil || v != 1 || calls != 2 {
+		t.Fatal("expecting 1, but got ", v, err, " calls=", calls)
+	}
+}
+
+func TestLoadingLruContext(t *testing.T) {
+	ll := NewLoadingLru(NewTypedLru[int, int](100, 0, nil), time.Hour, 10)
+	start := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		ll.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, int64, error) {
+			close(start)
+			<-done
+			return 0, 0, context.Canceled
+		})
+	}()
+	<-start
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := ll.GetOrLoad(ctx, 1, nil); err != context.DeadlineExceeded {
+		t.Fatal("expecting DeadlineExceeded, but got ", err)
+	}
+	close(done)
+
+	waitLoads(ll)
+
+	// context errors are not cached
+	v, err := ll.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, int64, error) {
+		return 5, 1, nil
+	})
+	if err != nil || v != 5 {
+		t.Fatal("expecting 5, but got ", v, err)
+	}
+}
+
+func TestLoadingLruLeaderCanceled(t *testing.T) {
+	ll := NewLoadingLru(NewTypedLru[int, int](100, 0, nil), time.Hour, 10)
+	start := make(chan struct{})
+	done := make(chan struct{})
+	loader := func(ctx context.Context, k int) (int, int64, error) {
+		close(start)
+		<-done
+		return k, 1, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := ll.GetOrLoad(ctx, 7, loader)
+		errs <- err
+	}()
+	<-start
+
+	res := make(chan int)
+	go func() {
+		v, err := ll.GetOrLoad(context.Background(), 7, nil)
+		if err != nil {
+			t.Error("unexpected error ", err)
+		}
+		res <- v
+	}()
+
+	// the first caller stops waiting, but the load goes on
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatal("expecting Canceled, but got ", err)
+	}
+	close(done)
+	if v := <-res; v != 7 {
+		t.Fatal("expecting 7, but got ", v)
+	}
+}
+
+func TestLoadingLruSuperseded(t *testing.T) {
+	ll := NewLoadingLru(NewTypedLru[int, int](100, 0, nil), 0, 0)
+	for _, del := range []bool{false, true} {
+		ll.Delete(1)
+		start := make(chan struct{})
+		done := make(chan struct{})
+		res := make(chan int)
+		go func() {
+			v, _ := ll.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, int64, error) {
+				close(start)
+				<-done
+				return 111, 1, nil
+			})
+			res <- v
+		}()
+		<-start
+
+		ll.lock.Lock()
+		c := ll.calls[1]
+		ll.lock.Unlock()
+		if del {
+			ll.Delete(1)
+		} else {
+			ll.Put(1, 222, 1)
+		}
+		close(done)
+
+		// the waiters get the load result, but it is not stored
+		if v := <-res; v != 111 {
+			t.Fatal("expecting 111 for the waiter, but got ", v)
+		}
+		<-c.done
+		v, ok := ll.Get(1)
+		if del && ok || !del && v != 222 {
+			t.Fatal("the load result must not be stored, but got ", v, ok)
+		}
+	}
+}
+
+func TestLoadingLruPanic(t *testing.T) {
+	ll := NewLoadingLru(NewTypedLru[int, int](100, 0, nil), 0, 0)
+	_, err := ll.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, int64, error) {
+		panic("boom")
+	})
+	if err == nil || ll.Len() != 0 {
+		t.Fatal("expecting the panic error, but got ", err)
+	}
+}
+
+func TestLoadingLruRevalidate(t *testing.T) {
+	var lock sync.Mutex
+	now := time.Now()
+	clck := func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		lock.Lock()
+		now = now.Add(d)
+		lock.Unlock()
+	}
+
+	ll := NewLoadingLru(NewLruWithClock[int, int](100, 0, nil, clck), time.Hour, 10)
+	ll.SetRevalidate(time.Second, time.Minute)
+	var calls int32
+	reload := make(chan struct{}, 1)
+	loader := func(ctx context.Context, k int) (int, int64, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-reload
+		}
+		if n == 3 {
+			return 0, 0, errors.New("reload failed")
+		}
+		return int(n), 1, nil
+	}
+
+	if v, stale, err := ll.GetOrLoadStale(context.Background(), 1, loader); v != 1 || stale || err != nil {
+		t.Fatal("expecting fresh 1, but got ", v, stale, err)
+	}
+
+	advance(2 * time.Second)
+	if v, stale, ok := ll.GetStale(1); v != 1 || !stale || !ok {
+		t.Fatal("expecting stale 1, but got ", v, stale, ok)
+	}
+	for i := 0; i < 5; i++ {
+		if v, stale, err := ll.GetOrLoadStale(context.Background(), 1, loader); v != 1 || !stale || err != nil {
+			t.Fatal("expecting stale 1, but got ", v, stale, err)
+		}
+	}
+	reload <- struct{}{}
+	waitLoads(ll)
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatal("expecting single reload, but calls=", calls)
+	}
+	if v, stale, err := ll.GetOrLoadStale(context.Background(), 1, loader); v != 2 || stale || err != nil {
+		t.Fatal("expecting fresh 2, but got ", v, stale, err)
+	}
+	if v, stale, ok := ll.GetStale(1); v != 2 || stale || !ok {
+		t.Fatal("expecting fresh 2, but got ", v, stale, ok)
+	}
+
+	// failed reload keeps the stale value
+	advance(2 * time.Second)
+	if v, _ := ll.GetOrLoad(context.Background(), 1, loader); v != 2 {
+		t.Fatal("expecting stale 2, but got ", v)
+	}
+	reload <- struct{}{}
+	waitLoads(ll)
+	if v, stale, err := ll.GetOrLoadStale(context.Background(), 1, loader); v != 2 || !stale || err != nil {
+		t.Fatal("expecting stale 2, but got ", v, stale, err)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatal("the error must be cached, but calls=", calls)
+	}
+
+	// the value is dead after hard TTL, and the error is not cached anymore
+	advance(time.Hour + time.Second)
+	reload <- struct{}{}
+	if v, stale, err := ll.GetOrLoadStale(context.Background(), 1, loader); v != 4 || stale || err != nil {
+		t.Fatal("expecting fresh 4, but got ", v, stale, err)
+	}
+}
+
+func waitLoads[K comparable, V any](ll *LoadingLru[K, V]) {
+	for i := 0; i < 1000; i++ {
+		ll.lock.Lock()
+		n := len(ll.calls)
+		ll.lock.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoadingLruRevalidatePut(t *testing.T) {
+	now := time.Now()
+	var lock sync.Mutex
+	clck := func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		return now
+	}
+	ll := NewLoadingLru(NewLruWithClock[int, int](100, 0, nil, clck), 0, 0)
+	ll.SetRevalidate(time.Second, time.Minute)
+	ll.Put(1, 1, 1)
+
+	lock.Lock()
+	now = now.Add(2 * time.Second)
+	lock.Unlock()
+	reload := make(chan struct{})
+	v, stale, err := ll.GetOrLoadStale(context.Background(), 1, func(ctx context.Context, k int) (int, int64, error) {
+		<-reload
+		return 111, 1, nil
+	})
+	if v != 1 || !stale || err != nil {
+		t.Fatal("expecting stale 1, but got ", v, stale, err)
+	}
+
+	ll.lock.Lock()
+	c := ll.calls[1]
+	ll.lock.Unlock()
+	ll.Put(1, 222, 1)
+	close(reload)
+	<-c.done
+
+	// the reload started before Put must not replace the newer value
+	if v, stale, ok := ll.GetStale(1); v != 222 || stale || !ok {
+		t.Fatal("expecting fresh 222, but got ", v, stale, ok)
+	}
+}
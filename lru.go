@@ -1,38 +1,120 @@
 package container
 
 import (
+	"container/heap"
+	"fmt"
 	"time"
 )
 
 type (
-	lru_element struct {
-		prev *lru_element
-		next *lru_element
-		v    LruValue
+	lru_element[K comparable, V any] struct {
+		prev *lru_element[K, V]
+		next *lru_element[K, V]
+		v    TypedLruValue[K, V]
+		// hidx is the element index in the ttl heap, -1 if the element
+		// doesn't have own deadline
+		hidx int
+		// seg is the list the element belongs to, it is used by the
+		// containers which keep elements in several lists (see Slru)
+		seg uint8
+		// refs is the number of the element references (see Acquire)
+		refs int32
+		// zombie is true if the element is deleted from the container, but
+		// it is still referenced, zr and zcb are the delete parameters
+		zombie bool
+		zcb    bool
+		zr     LruEvictReason
 	}
 
-	LruValue struct {
+	// TypedLruValue holds a key-value pair stored in Lru together with its
+	// size and the time it was touched last time.
+	TypedLruValue[K comparable, V any] struct {
 		size int64
 		ts   time.Time
-		key  interface{}
-		val  interface{}
+		exp  time.Time
+		key  K
+		val  V
 	}
 
-	// Lru is "least recently used" container, which allows to control the
-	// element by size, time of touch, or both. It keeps recently used items
-	// near the top of cache.
-	Lru struct {
-		head    *lru_element
-		pool    *lru_element
-		kvMap   map[interface{}]*lru_element
+	// TypedLru is "least recently used" container, which allows to control
+	// the element by size, time of touch, or both. It keeps recently used
+	// items near the top of cache. The keys of type K and values of type V are
+	// stored as is, so no type assertions are needed on the caller side.
+	TypedLru[K comparable, V any] struct {
+		head    *lru_element[K, V]
+		pool    lru_pool[K, V]
+		kvMap   map[K]*lru_element[K, V]
+		ttlHeap lru_ttl_heap[K, V]
 		size    int64
 		maxSize int64
 		maxDur  time.Duration
-		cback   LruDeleteCallback
+		cback   TypedLruDeleteCallback[K, V]
+		ecback  LruEvictCallback[K, V]
+		// evictHook is the internal evict callback, which gets the whole
+		// element value, see TieredLru
+		evictHook func(v *TypedLruValue[K, V], r LruEvictReason)
+		stats     LruStats
+		sizeF     LruSizeF[K, V]
+
+		// clockNow is the clock function. It used to get the current time
+		clockNow TsClockNowF
+	}
+
+	// LruCache is the interface implemented by Lru and the containers with
+	// other eviction policies, so they can be used interchangeably
+	LruCache[K comparable, V any] interface {
+		Put(k K, v V, size int64)
+		Get(k K) *TypedLruValue[K, V]
+		Peek(k K) *TypedLruValue[K, V]
+		Delete(k K)
+		DeleteNoCallback(k K)
+		Clear(cb bool)
+		Iterate(f TypedLruCallback[K, V])
+		Size() int64
+		Len() int
+		SweepByTime() time.Time
+	}
+
+	TypedLruDeleteCallback[K comparable, V any] func(k K, v V)
+	TypedLruCallback[K comparable, V any]       func(k K, v V) bool
+
+	// Lru, LruValue, LruDeleteCallback and LruCallback are the untyped
+	// versions of the containers, where keys and values are interface{}
+	Lru               = TypedLru[interface{}, interface{}]
+	LruValue          = TypedLruValue[interface{}, interface{}]
+	LruDeleteCallback = TypedLruDeleteCallback[interface{}, interface{}]
+	LruCallback       = TypedLruCallback[interface{}, interface{}]
+
+	// LruEvictCallback is the delete callback variant, which receives the
+	// element size and the reason why the element was pulled out of the cache
+	LruEvictCallback[K comparable, V any] func(k K, v V, size int64, r LruEvictReason)
+
+	// LruEvictReason describes why an element was pulled out of the cache
+	LruEvictReason int
+
+	// lru_pool is the free list of the deleted elements, linked by next. It
+	// keeps up to max elements for reuse.
+	lru_pool[K comparable, V any] struct {
+		head *lru_element[K, V]
+		len  int
+		max  int
 	}
 
-	LruDeleteCallback func(k, v interface{})
-	LruCallback       func(k, v interface{}) bool
+	// lru_ttl_heap keeps elements with own deadline ordered by the deadline
+	lru_ttl_heap[K comparable, V any] []*lru_element[K, V]
+)
+
+const (
+	// LruExpired - the element was not touched for maxDur, or its own deadline passed
+	LruExpired LruEvictReason = iota
+	// LruCapacity - the element was pulled out to free space for new one
+	LruCapacity
+	// LruReplaced - the element was replaced by Put for the same key
+	LruReplaced
+	// LruDeleted - the element was deleted explicitly
+	LruDeleted
+	// LruCleared - the element was deleted by Clear
+	LruCleared
 )
 
 var nilTime = time.Time{}
@@ -42,52 +124,108 @@ var nilTime = time.Time{}
 // invoked when an element is pulled out of the cache. It can be nil
 //
 // Timeout to could be 0, what means don't use it at all
+//
+// NewLru keeps the untyped API, where keys and values are interface{}. Use
+// NewTypedLru for the type-safe version.
 func NewLru(maxSize int64, to time.Duration, cback LruDeleteCallback) *Lru {
-	l := new(Lru)
-	l.kvMap = make(map[interface{}]*lru_element)
+	return NewTypedLru(maxSize, to, cback)
+}
+
+// NewTypedLru same as NewLru, but creates the Lru container for keys of type K
+// and values of type V.
+func NewTypedLru[K comparable, V any](maxSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V]) *TypedLru[K, V] {
+	return NewLruWithClock(maxSize, to, cback, time.Now)
+}
+
+// NewLruWithClock same as NewTypedLru, but allows to provide the clock function
+// clck, which is used for discovering current time instead of time.Now()
+func NewLruWithClock[K comparable, V any](maxSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V], clck TsClockNowF) *TypedLru[K, V] {
+	l := new(TypedLru[K, V])
+	l.kvMap = make(map[K]*lru_element[K, V])
 	l.maxSize = maxSize
 	l.maxDur = to
 	l.cback = cback
+	l.clockNow = clck
+	l.pool.max = 1
 	return l
 }
 
-func (l *Lru) Put(k, v interface{}, size int64) {
+// SetEvictCallback sets the callback which is invoked together with the delete
+// callback (provided in the constructor) when an element is pulled out of the
+// cache. Unlike the delete callback it receives the element size and the
+// reason of the deletion. The callback can be nil.
+func (l *TypedLru[K, V]) SetEvictCallback(ecback LruEvictCallback[K, V]) {
+	l.ecback = ecback
+}
+
+func (l *TypedLru[K, V]) Put(k K, v V, size int64) {
+	l.PutWithTTL(k, v, size, 0)
+}
+
+// PutWithTTL places the key-value pair into the cache the same way as Put does,
+// but the element gets its own deadline, which is ttl after the moment it is
+// put. The element is pulled out by SweepByTime when the deadline passes, or
+// when it is not touched for maxDur (if it is set), whatever happens first.
+// ttl 0 means the element doesn't have own deadline.
+func (l *TypedLru[K, V]) PutWithTTL(k K, v V, size int64, ttl time.Duration) {
 	e, ok := l.kvMap[k]
 	if ok {
-		l.delete(e, true)
+		l.delete(e, LruReplaced, true)
 	}
 
 	tm := l.SweepByTime()
-	l.sweepBySize(size)
+	l.put(k, v, size, ttl, tm)
+}
 
-	if l.pool != nil {
-		e = l.pool
-		l.pool = nil
-	} else {
-		e = new(lru_element)
+// put places the key-value pair to the head of the list, tm is the current time
+// returned by SweepByTime
+func (l *TypedLru[K, V]) put(k K, v V, size int64, ttl time.Duration, tm time.Time) {
+	l.stats.Puts++
+	e, ok := l.kvMap[k]
+	if ok {
+		l.delete(e, LruReplaced, true)
 	}
+	l.sweepBySize(size)
+
+	e = l.newElement()
 	e.v.key = k
 	e.v.val = v
 	e.v.ts = tm
 	e.v.size = size
+	e.v.exp = nilTime
+	e.hidx = -1
+	if ttl > 0 {
+		if tm.IsZero() {
+			tm = l.clockNow()
+		}
+		e.v.exp = tm.Add(ttl)
+		heap.Push(&l.ttlHeap, e)
+	}
 	l.head = addToHead(l.head, e)
 	l.kvMap[k] = e
 	l.size += size
 }
 
-func (l *Lru) Get(k interface{}) *LruValue {
-	ts := l.SweepByTime()
+func (l *TypedLru[K, V]) Get(k K) *TypedLruValue[K, V] {
+	return l.get(k, l.SweepByTime())
+}
+
+// get moves the element to the head of the list, ts is the current time
+// returned by SweepByTime
+func (l *TypedLru[K, V]) get(k K, ts time.Time) *TypedLruValue[K, V] {
 	e, ok := l.kvMap[k]
 	if ok {
 		l.head = removeFromList(l.head, e)
 		l.head = addToHead(l.head, e)
 		e.v.ts = ts
+		l.stats.Hits++
 		return &e.v
 	}
+	l.stats.Misses++
 	return nil
 }
 
-func (l *Lru) Peek(k interface{}) *LruValue {
+func (l *TypedLru[K, V]) Peek(k K) *TypedLruValue[K, V] {
 	l.SweepByTime()
 	e, ok := l.kvMap[k]
 	if ok {
@@ -96,25 +234,25 @@ func (l *Lru) Peek(k interface{}) *LruValue {
 	return nil
 }
 
-func (l *Lru) Delete(k interface{}) {
+func (l *TypedLru[K, V]) Delete(k K) {
 	l.SweepByTime()
 	e, ok := l.kvMap[k]
 	if ok {
-		l.delete(e, true)
+		l.delete(e, LruDeleted, true)
 	}
 }
 
-func (l *Lru) DeleteNoCallback(k interface{}) {
+func (l *TypedLru[K, V]) DeleteNoCallback(k K) {
 	l.SweepByTime()
 	e, ok := l.kvMap[k]
 	if ok {
-		l.delete(e, false)
+		l.delete(e, LruDeleted, false)
 	}
 }
 
-func (l *Lru) Clear(cb bool) {
+func (l *TypedLru[K, V]) Clear(cb bool) {
 	for l.head != nil {
-		l.delete(l.head, cb)
+		l.delete(l.head, LruCleared, cb)
 	}
 }
 
@@ -123,7 +261,7 @@ func (l *Lru) Clear(cb bool) {
 // or all elements are visited.
 //
 // Note: the modifications of the container must not allowed in the f
-func (l *Lru) Iterate(f LruCallback) {
+func (l *TypedLru[K, V]) Iterate(f TypedLruCallback[K, V]) {
 	h := l.head
 	for h != nil {
 		if !f(h.v.key, h.v.val) {
@@ -136,55 +274,159 @@ func (l *Lru) Iterate(f LruCallback) {
 	}
 }
 
-func (l *Lru) Size() int64 {
+func (l *TypedLru[K, V]) Size() int64 {
 	return l.size
 }
 
-func (l *Lru) Len() int {
+func (l *TypedLru[K, V]) Len() int {
 	return len(l.kvMap)
 }
 
-func (l *Lru) SweepByTime() time.Time {
-	if l.maxDur == 0 {
+// SweepByTime pulls out the elements which were not touched for maxDur and
+// the elements whose own deadline (see PutWithTTL) is passed. It returns the
+// current time, or zero time if the container doesn't control time at all.
+func (l *TypedLru[K, V]) SweepByTime() time.Time {
+	if l.maxDur == 0 && len(l.ttlHeap) == 0 {
 		return nilTime
 	}
-	tm := time.Now()
-	for l.head != nil && tm.Sub(l.head.prev.v.ts) > l.maxDur {
-		last := l.head.prev
-		l.delete(last, true)
+	tm := l.clockNow()
+	if l.maxDur > 0 && l.head != nil {
+		e := l.head.prev
+		for n := len(l.kvMap); n > 0 && tm.Sub(e.v.ts) > l.maxDur; n-- {
+			prev := e.prev
+			if e.refs == 0 {
+				l.delete(e, LruExpired, true)
+			}
+			e = prev
+		}
+	}
+
+	var pinned []*lru_element[K, V]
+	for len(l.ttlHeap) > 0 && tm.After(l.ttlHeap[0].v.exp) {
+		e := l.ttlHeap[0]
+		if e.refs > 0 {
+			heap.Pop(&l.ttlHeap)
+			pinned = append(pinned, e)
+			continue
+		}
+		l.delete(e, LruExpired, true)
+	}
+	for _, e := range pinned {
+		heap.Push(&l.ttlHeap, e)
 	}
 	return tm
 }
 
 // GetData returns underlying container data for the LRU
-func (l *Lru) GetData() map[interface{}]interface{} {
-	res := make(map[interface{}]interface{}, len(l.kvMap))
+func (l *TypedLru[K, V]) GetData() map[K]V {
+	res := make(map[K]V, len(l.kvMap))
 	for k, v := range l.kvMap {
 		res[k] = v.v.val
 	}
 	return res
 }
 
-func (l *Lru) sweepBySize(addSize int64) {
-	for l.head != nil && l.size+addSize > l.maxSize {
-		last := l.head.prev
-		l.delete(last, true)
+// sweepBySize pulls out the least recently used elements, which are not
+// referenced, until there is enough space for addSize
+func (l *TypedLru[K, V]) sweepBySize(addSize int64) {
+	if l.head == nil {
+		return
+	}
+	e := l.head.prev
+	for n := len(l.kvMap); n > 0 && l.size+addSize > l.maxSize; n-- {
+		prev := e.prev
+		if e.refs == 0 {
+			l.delete(e, LruCapacity, true)
+		}
+		e = prev
 	}
 }
 
-func (l *Lru) delete(e *lru_element, cb bool) {
+func (l *TypedLru[K, V]) delete(e *lru_element[K, V], r LruEvictReason, cb bool) {
 	l.head = removeFromList(l.head, e)
 	l.size -= e.v.size
-	l.pool = e
+	if e.hidx >= 0 {
+		heap.Remove(&l.ttlHeap, e.hidx)
+	}
 	delete(l.kvMap, e.v.key)
+	l.stats.count(r)
+	if e.refs > 0 {
+		e.zombie = true
+		e.zr = r
+		e.zcb = cb
+		return
+	}
+	l.dispose(e, r, cb)
+}
+
+// dispose notifies the callbacks about the element deletion and returns the
+// element to the pool
+func (l *TypedLru[K, V]) dispose(e *lru_element[K, V], r LruEvictReason, cb bool) {
 	if cb && l.cback != nil {
 		l.cback(e.v.key, e.v.val)
 	}
-	e.v.key = nil
-	e.v.val = nil
+	if cb && l.ecback != nil {
+		l.ecback(e.v.key, e.v.val, e.v.size, r)
+	}
+	if cb && l.evictHook != nil {
+		l.evictHook(&e.v, r)
+	}
+	l.pool.put(e)
+}
+
+// newElement returns an element from the pool, or allocates new one if the
+// pool is empty
+func (l *TypedLru[K, V]) newElement() *lru_element[K, V] {
+	return l.pool.get()
+}
+
+// SetPoolSize sets the maximum number of the deleted elements kept for reuse.
+// The bigger pool allows to avoid memory allocations in Put after bursts of
+// evictions, the default pool size is 1. Negative n is treated as 0.
+func (l *TypedLru[K, V]) SetPoolSize(n int) {
+	l.pool.setSize(n)
+}
+
+// get returns an element from the pool, or allocates new one if the pool is
+// empty
+func (p *lru_pool[K, V]) get() *lru_element[K, V] {
+	e := p.head
+	if e == nil {
+		return new(lru_element[K, V])
+	}
+	p.head = e.next
+	p.len--
+	e.next = nil
+	return e
 }
 
-func removeFromList(head, e *lru_element) *lru_element {
+// put zeroes the element key and value, so they can be collected by GC, and
+// keeps the element for reuse if the pool is not full
+func (p *lru_pool[K, V]) put(e *lru_element[K, V]) {
+	var zk K
+	var zv V
+	e.v.key = zk
+	e.v.val = zv
+	e.prev = nil
+	e.next = nil
+	if p.len < p.max {
+		e.next = p.head
+		p.head = e
+		p.len++
+	}
+}
+
+func (p *lru_pool[K, V]) setSize(n int) {
+	p.max = max(n, 0)
+	for p.len > p.max {
+		e := p.head
+		p.head = e.next
+		e.next = nil
+		p.len--
+	}
+}
+
+func removeFromList[K comparable, V any](head, e *lru_element[K, V]) *lru_element[K, V] {
 	if e == head && head.next == head {
 		head = nil
 	}
@@ -197,7 +439,7 @@ func removeFromList(head, e *lru_element) *lru_element {
 }
 
 // add n to list with head and returns new head
-func addToHead(head *lru_element, n *lru_element) *lru_element {
+func addToHead[K comparable, V any](head *lru_element[K, V], n *lru_element[K, V]) *lru_element[K, V] {
 	if n == nil {
 		return head
 	}
@@ -213,18 +455,70 @@ func addToHead(head *lru_element, n *lru_element) *lru_element {
 	return n
 }
 
-func (v *LruValue) Key() interface{} {
+func (v *TypedLruValue[K, V]) Key() K {
 	return v.key
 }
 
-func (v *LruValue) Val() interface{} {
+func (v *TypedLruValue[K, V]) Val() V {
 	return v.val
 }
 
-func (v *LruValue) Size() int64 {
+func (v *TypedLruValue[K, V]) Size() int64 {
 	return v.size
 }
 
-func (v *LruValue) TouchedAt() time.Time {
+func (v *TypedLruValue[K, V]) TouchedAt() time.Time {
 	return v.ts
 }
+
+// ExpiresAt returns the element own deadline, or zero time if the element
+// doesn't have it
+func (v *TypedLruValue[K, V]) ExpiresAt() time.Time {
+	return v.exp
+}
+
+func (r LruEvictReason) String() string {
+	switch r {
+	case LruExpired:
+		return "Expired"
+	case LruCapacity:
+		return "Capacity"
+	case LruReplaced:
+		return "Replaced"
+	case LruDeleted:
+		return "Deleted"
+	case LruCleared:
+		return "Cleared"
+	}
+	return fmt.Sprint("LruEvictReason(", int(r), ")")
+}
+
+func (h lru_ttl_heap[K, V]) Len() int {
+	return len(h)
+}
+
+func (h lru_ttl_heap[K, V]) Less(i, j int) bool {
+	return h[i].v.exp.Before(h[j].v.exp)
+}
+
+func (h lru_ttl_heap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].hidx = i
+	h[j].hidx = j
+}
+
+func (h *lru_ttl_heap[K, V]) Push(x interface{}) {
+	e := x.(*lru_element[K, V])
+	e.hidx = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lru_ttl_heap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.hidx = -1
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,64 @@
+package container
+
+// NewLruValue returns LruValue for the key-value pair with the size, it can
+// be used for PutMany
+func NewLruValue[K comparable, V any](k K, v V, size int64) TypedLruValue[K, V] {
+	return TypedLruValue[K, V]{key: k, val: v, size: size}
+}
+
+// PutMany places the key-value pairs into the cache in the order they are
+// provided, so the last one becomes the most recently used. The expired
+// elements are swept once for the whole batch.
+func (l *TypedLru[K, V]) PutMany(vals []TypedLruValue[K, V]) {
+	tm := l.SweepByTime()
+	for i := range vals {
+		l.put(vals[i].key, vals[i].val, vals[i].size, 0, tm)
+	}
+}
+
+// GetMany same as Get, but for number of keys. It returns the slice of
+// values in the order of keys, the value is nil if there is no such key. The
+// expired elements are swept once for the whole batch.
+func (l *TypedLru[K, V]) GetMany(keys []K) []*TypedLruValue[K, V] {
+	ts := l.SweepByTime()
+	res := make([]*TypedLruValue[K, V], len(keys))
+	for i, k := range keys {
+		res[i] = l.get(k, ts)
+	}
+	return res
+}
+
+// DeleteMany deletes the keys from the cache, the delete callback is invoked
+// for every deleted element. It returns number of deleted elements.
+func (l *TypedLru[K, V]) DeleteMany(keys []K) int {
+	l.SweepByTime()
+	cnt := 0
+	for _, k := range keys {
+		if e, ok := l.kvMap[k]; ok {
+			l.delete(e, LruDeleted, true)
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// DeleteIf walks over the elements in LRU order and deletes every element,
+// for which f() returns true. The delete callback is invoked for every
+// deleted element. It returns number of deleted elements.
+//
+// Note: the modifications of the container must not allowed in the f and
+// in the delete callback
+func (l *TypedLru[K, V]) DeleteIf(f func(k K, v V) bool) int {
+	l.SweepByTime()
+	cnt := 0
+	h := l.head
+	for n := len(l.kvMap); n > 0; n-- {
+		next := h.next
+		if f(h.v.key, h.v.val) {
+			l.delete(h, LruDeleted, true)
+			cnt++
+		}
+		h = next
+	}
+	return cnt
+}
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPutGetMany(t *testing.T) {
+	var deleted []string
+	l := NewTypedLru(3, time.Hour, func(k string, v int) {
+		deleted = append(deleted, k)
+	})
+	l.Put("a", 1, 1)
+	l.PutMany([]TypedLruValue[string, int]{
+		NewLruValue("a", 11, 1),
+		NewLruValue("b", 2, 1),
+		NewLruValue("c", 3, 1),
+		NewLruValue("d", 4, 1),
+	})
+	if l.Len() != 3 || len(deleted) != 2 || deleted[0] != "a" || deleted[1] != "a" {
+		t.Fatal("wrong len=", l.Len(), " or deleted=", deleted)
+	}
+
+	res := l.GetMany([]string{"b", "a", "d"})
+	if len(res) != 3 || res[0].Val() != 2 || res[1] != nil || res[2].Val() != 4 {
+		t.Fatal("wrong result ", res)
+	}
+	if l.head.v.key != "d" || l.head.prev.v.key != "c" {
+		t.Fatal("d must be the most recently used, c - the least one")
+	}
+	if st := l.Stats(); st.Hits != 2 || st.Misses != 1 {
+		t.Fatal("wrong stats ", st)
+	}
+
+	if n := l.DeleteMany([]string{"a", "b", "c"}); n != 2 || l.Len() != 1 {
+		t.Fatal("expecting 2 deleted, but ", n)
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+	var deleted []string
+	l := NewTypedLru(100, time.Hour, func(k string, v int) {
+		deleted = append(deleted, k)
+	})
+	for _, k := range []string{"t1:a", "t2:a", "t1:b", "t1:c", "t2:b", "t1:d"} {
+		l.Put(k, len(deleted), 1)
+	}
+
+	n := l.DeleteIf(func(k string, v int) bool {
+		return strings.HasPrefix(k, "t1:")
+	})
+	if n != 4 || l.Len() != 2 || len(deleted) != 4 || deleted[0] != "t1:d" || deleted[3] != "t1:a" {
+		t.Fatal("wrong n=", n, " or deleted=", deleted)
+	}
+
+	var keys []string
+	l.Iterate(func(k string, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != "t2:b" || keys[1] != "t2:a" {
+		t.Fatal("wrong keys ", keys)
+	}
+
+	if l.DeleteIf(func(k string, v int) bool { return true }) != 2 || l.Len() != 0 || l.head != nil {
+		t.Fatal("must be empty")
+	}
+	if l.DeleteIf(func(k string, v int) bool { return true }) != 0 {
+		t.Fatal("nothing to delete")
+	}
+}
@@ -0,0 +1,424 @@
+package container
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+type (
+	// InvalidationBus delivers the key invalidations between the cache
+	// replicas. Every replica has its own bus endpoint, the keys published by
+	// an endpoint are delivered to all other endpoints, but not to the sender.
+	InvalidationBus interface {
+		// Publish sends the key invalidation to the peers
+		Publish(key []byte) error
+
+		// Subscribe sets the function which is called for every key
+		// invalidation received from the peers. The function is called from
+		// the bus goroutine.
+		Subscribe(f func(key []byte))
+
+		// Close disconnects the endpoint from the bus
+		Close() error
+	}
+
+	// InvalidatingLru is the concurrency-safe wrapper over Lru, which
+	// publishes the keys invalidations to InvalidationBus on Put and Delete,
+	// and deletes the keys invalidated by the peers. The keys deleted by the
+	// peers invalidations are not published again.
+	InvalidatingLru[K comparable, V any] struct {
+		lock sync.Mutex
+		lru  *TypedLru[K, V]
+		bus  InvalidationBus
+		kc   LruCodec[K]
+		err  error
+	}
+
+	// ChanBus is the in-process InvalidationBus hub, which delivers the keys
+	// over channels. See ChanBus.Endpoint
+	ChanBus struct {
+		lock sync.Mutex
+		eps  map[*chan_bus_endpoint]struct{}
+	}
+
+	chan_bus_endpoint struct {
+		hub  *ChanBus
+		ch   chan []byte
+		lock sync.Mutex
+		f    func(key []byte)
+		done chan struct{}
+		once sync.Once
+	}
+
+	// NetBusServer is the InvalidationBus hub, which relays the keys between
+	// the endpoints connected to it over TCP or Unix socket, see DialNetBus
+	NetBusServer struct {
+		ln   net.Listener
+		lock sync.Mutex
+		// conns are the connected endpoints with their outgoing queues
+		conns map[net.Conn]chan []byte
+		// closed is set by Close, the connections accepted after that are
+		// closed immediately
+		closed bool
+		wg     sync.WaitGroup
+	}
+
+	net_bus_endpoint struct {
+		conn  net.Conn
+		wlock sync.Mutex
+		lock  sync.Mutex
+		f     func(key []byte)
+		done  chan struct{}
+	}
+)
+
+const (
+	chanBusCapacity = 1024
+	netBusQueueLen  = 1024
+	netBusMaxKeyLen = 1 << 20
+)
+
+var errBusClosed = errors.New("the bus endpoint is closed")
+
+// NewInvalidatingLru creates new InvalidatingLru on top of l, the keys are
+// encoded by kc for sending them over bus. The l must not be used directly
+// after that.
+func NewInvalidatingLru[K comparable, V any](l *TypedLru[K, V], bus InvalidationBus, kc LruCodec[K]) *InvalidatingLru[K, V] {
+	il := new(InvalidatingLru[K, V])
+	il.lru = l
+	il.bus = bus
+	il.kc = kc
+	bus.Subscribe(il.onInvalidate)
+	return il
+}
+
+// Put places the key-value pair into the cache and publishes the key
+// invalidation
+func (il *InvalidatingLru[K, V]) Put(k K, v V, size int64) error {
+	il.lock.Lock()
+	il.lru.Put(k, v, size)
+	il.lock.Unlock()
+	return il.publish(k)
+}
+
+// Get returns the value for k, the second returned value is false if there
+// is no such key
+func (il *InvalidatingLru[K, V]) Get(k K) (V, bool) {
+	il.lock.Lock()
+	defer il.lock.Unlock()
+	if v := il.lru.Get(k); v != nil {
+		return v.Val(), true
+	}
+	var zv V
+	return zv, false
+}
+
+// Delete deletes the key from the cache and publishes the key invalidation
+func (il *InvalidatingLru[K, V]) Delete(k K) error {
+	il.lock.Lock()
+	il.lru.Delete(k)
+	il.lock.Unlock()
+	return il.publish(k)
+}
+
+func (il *InvalidatingLru[K, V]) Len() int {
+	il.lock.Lock()
+	defer il.lock.Unlock()
+	return il.lru.Len()
+}
+
+// Err returns the last error happened while an invalidation was received
+func (il *InvalidatingLru[K, V]) Err() error {
+	il.lock.Lock()
+	defer il.lock.Unlock()
+	return il.err
+}
+
+func (il *InvalidatingLru[K, V]) publish(k K) error {
+	kb, err := il.kc.Encode(k)
+	if err != nil {
+		return fmt.Errorf("could not encode key %v: %w", k, err)
+	}
+	return il.bus.Publish(kb)
+}
+
+func (il *InvalidatingLru[K, V]) onInvalidate(kb []byte) {
+	k, err := il.kc.Decode(kb)
+	il.lock.Lock()
+	defer il.lock.Unlock()
+	if err != nil {
+		il.err = fmt.Errorf("could not decode invalidated key: %w", err)
+		return
+	}
+	il.lru.Delete(k)
+}
+
+// NewChanBus creates new in-process InvalidationBus hub
+func NewChanBus() *ChanBus {
+	return &ChanBus{eps: make(map[*chan_bus_endpoint]struct{})}
+}
+
+// Endpoint returns new endpoint connected to the hub
+func (cb *ChanBus) Endpoint() InvalidationBus {
+	ep := &chan_bus_endpoint{hub: cb, ch: make(chan []byte, chanBusCapacity), done: make(chan struct{})}
+	cb.lock.Lock()
+	cb.eps[ep] = struct{}{}
+	cb.lock.Unlock()
+	go ep.run()
+	return ep
+}
+
+// Publish sends the copy of key to all other endpoints of the hub. It blocks
+// if a peer doesn't keep up with the invalidations.
+func (ep *chan_bus_endpoint) Publish(key []byte) error {
+	select {
+	case <-ep.done:
+		return errBusClosed
+	default:
+	}
+	k := append([]byte(nil), key...)
+	ep.hub.lock.Lock()
+	defer ep.hub.lock.Unlock()
+	for peer := range ep.hub.eps {
+		if peer == ep {
+			continue
+		}
+		select {
+		case peer.ch <- k:
+		case <-peer.done:
+		}
+	}
+	return nil
+}
+
+func (ep *chan_bus_endpoint) Subscribe(f func(key []byte)) {
+	ep.lock.Lock()
+	ep.f = f
+	ep.lock.Unlock()
+}
+
+func (ep *chan_bus_endpoint) Close() error {
+	ep.once.Do(func() {
+		close(ep.done)
+		ep.hub.lock.Lock()
+		delete(ep.hub.eps, ep)
+		ep.hub.lock.Unlock()
+	})
+	return nil
+}
+
+func (ep *chan_bus_endpoint) run() {
+	for {
+		select {
+		case <-ep.done:
+			return
+		case k := <-ep.ch:
+			ep.lock.Lock()
+			f := ep.f
+			ep.lock.Unlock()
+			if f != nil {
+				f(k)
+			}
+		}
+	}
+}
+
+// NewNetBusServer starts new NetBusServer listening on the address addr of
+// the network (see net.Listen)
+func NewNetBusServer(network, addr string) (*NetBusServer, error) {
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &NetBusServer{ln: ln, conns: make(map[net.Conn]chan []byte)}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *NetBusServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Conns returns number of the connected endpoints
+func (s *NetBusServer) Conns() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.conns)
+}
+
+// Close stops the server and disconnects all the endpoints
+func (s *NetBusServer) Close() error {
+	err := s.ln.Close()
+	s.lock.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *NetBusServer) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		out := make(chan []byte, netBusQueueLen)
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = out
+		s.lock.Unlock()
+		s.wg.Add(2)
+		go s.relay(c)
+		go s.send(c, out)
+	}
+}
+
+// relay reads the keys from the connection c and queues them for sending to
+// all other connections. The peer which doesn't keep up with the queue is
+// disconnected, so it doesn't stop the relay for others.
+func (s *NetBusServer) relay(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.lock.Lock()
+		out := s.conns[c]
+		delete(s.conns, c)
+		s.lock.Unlock()
+		close(out)
+		c.Close()
+	}()
+
+	rd := bufio.NewReader(c)
+	for {
+		buf, err := readBusFrame(rd, nil)
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		for peer, out := range s.conns {
+			if peer == c {
+				continue
+			}
+			select {
+			case out <- buf:
+			default:
+				peer.Close()
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+// send writes the queued keys to the connection c until the queue is closed.
+// The connection is closed if a write fails.
+func (s *NetBusServer) send(c net.Conn, out chan []byte) {
+	defer s.wg.Done()
+	wr := bufio.NewWriter(c)
+	failed := false
+	for key := range out {
+		if failed {
+			continue
+		}
+		err := writeBusFrame(wr, key)
+		if err == nil && len(out) == 0 {
+			err = wr.Flush()
+		}
+		if err != nil {
+			failed = true
+			c.Close()
+		}
+	}
+}
+
+// DialNetBus connects to NetBusServer listening on addr of the network and
+// returns the bus endpoint
+func DialNetBus(network, addr string) (InvalidationBus, error) {
+	c, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	ep := &net_bus_endpoint{conn: c, done: make(chan struct{})}
+	go ep.run()
+	return ep, nil
+}
+
+func (ep *net_bus_endpoint) Publish(key []byte) error {
+	if len(key) > netBusMaxKeyLen {
+		return fmt.Errorf("the key is too long: %d bytes", len(key))
+	}
+	ep.wlock.Lock()
+	defer ep.wlock.Unlock()
+	return writeBusFrame(ep.conn, key)
+}
+
+func (ep *net_bus_endpoint) Subscribe(f func(key []byte)) {
+	ep.lock.Lock()
+	ep.f = f
+	ep.lock.Unlock()
+}
+
+// Close closes the connection and waits until the receiving goroutine is over
+func (ep *net_bus_endpoint) Close() error {
+	err := ep.conn.Close()
+	<-ep.done
+	return err
+}
+
+func (ep *net_bus_endpoint) run() {
+	defer close(ep.done)
+	rd := bufio.NewReader(ep.conn)
+	for {
+		buf, err := readBusFrame(rd, nil)
+		if err != nil {
+			return
+		}
+		ep.lock.Lock()
+		f := ep.f
+		ep.lock.Unlock()
+		if f != nil {
+			f(buf)
+		}
+	}
+}
+
+// writeBusFrame writes the key prefixed by its length
+func writeBusFrame(w io.Writer, key []byte) error {
+	buf := make([]byte, 4+len(key))
+	binary.BigEndian.PutUint32(buf, uint32(len(key)))
+	copy(buf[4:], key)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readBusFrame reads the key written by writeBusFrame, buf is used if it has
+// enough capacity
+func readBusFrame(r io.Reader, buf []byte) ([]byte, error) {
+	var ln [4]byte
+	if _, err := io.ReadFull(r, ln[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(ln[:]))
+	if n > netBusMaxKeyLen {
+		return nil, fmt.Errorf("the key is too long: %d bytes", n)
+	}
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
@@ -0,0 +1,207 @@
+package container
+
+import (
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, f func() bool) {
+	for i := 0; i < 5000; i++ {
+		if f() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("the condition is not met")
+}
+
+// testInvalidation checks the invalidations between 3 replicas connected by
+// the buses
+func testInvalidation(t *testing.T, buses []InvalidationBus) {
+	var lock sync.Mutex
+	deleted := 0
+	var ils []*InvalidatingLru[string, int]
+	for _, bus := range buses {
+		ils = append(ils, NewInvalidatingLru(NewTypedLru(100, time.Hour, func(k string, v int) {
+			lock.Lock()
+			deleted++
+			lock.Unlock()
+		}), bus, LruStringCodec{}))
+	}
+	for i, il := range ils {
+		il.lru.Put("a", i, 1)
+		il.lru.Put("b", i, 1)
+	}
+
+	if err := ils[0].Put("a", 100, 1); err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	waitFor(t, func() bool {
+		return ils[1].Len() == 1 && ils[2].Len() == 1
+	})
+	if v, ok := ils[0].Get("a"); !ok || v != 100 {
+		t.Fatal("the sender must keep its value")
+	}
+
+	if err := ils[1].Delete("b"); err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	waitFor(t, func() bool {
+		return ils[0].Len() == 1 && ils[2].Len() == 0
+	})
+
+	// no duplicate broadcasts: 0 deleted a (replaced) and b, 1 deleted a and b,
+	// 2 deleted a and b
+	time.Sleep(10 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if deleted != 6 || ils[0].Len() != 1 || ils[1].Len() != 0 {
+		t.Fatal("expecting 6 deleted, but ", deleted)
+	}
+}
+
+func TestChanBus(t *testing.T) {
+	hub := NewChanBus()
+	buses := []InvalidationBus{hub.Endpoint(), hub.Endpoint(), hub.Endpoint()}
+	testInvalidation(t, buses)
+	for _, b := range buses {
+		b.Close()
+	}
+	if buses[0].Publish([]byte("a")) == nil {
+		t.Fatal("expecting error for closed endpoint")
+	}
+}
+
+func testNetBus(t *testing.T, network, addr string) {
+	s, err := NewNetBusServer(network, addr)
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	defer s.Close()
+
+	var buses []InvalidationBus
+	for i := 0; i < 3; i++ {
+		b, err := DialNetBus(network, s.Addr().String())
+		if err != nil {
+			t.Fatal("unexpected error ", err)
+		}
+		defer b.Close()
+		buses = append(buses, b)
+	}
+	waitFor(t, func() bool { return s.Conns() == 3 })
+	testInvalidation(t, buses)
+
+	buses[2].Close()
+	waitFor(t, func() bool { return s.Conns() == 2 })
+}
+
+func TestNetBusTCP(t *testing.T) {
+	testNetBus(t, "tcp", "127.0.0.1:0")
+}
+
+func TestNetBusUnix(t *testing.T) {
+	testNetBus(t, "unix", filepath.Join(t.TempDir(), "bus.sock"))
+}
+
+func TestNetBusStalledPeer(t *testing.T) {
+	s, err := NewNetBusServer("unix", filepath.Join(t.TempDir(), "bus.sock"))
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+
+	// the stalled peer never reads
+	stalled, err := net.Dial("unix", s.Addr().String())
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	defer stalled.Close()
+
+	var buses []InvalidationBus
+	for i := 0; i < 2; i++ {
+		b, err := DialNetBus("unix", s.Addr().String())
+		if err != nil {
+			t.Fatal("unexpected error ", err)
+		}
+		defer b.Close()
+		buses = append(buses, b)
+	}
+	var received atomic.Int32
+	buses[1].Subscribe(func(key []byte) {
+		received.Add(1)
+	})
+	waitFor(t, func() bool { return s.Conns() == 3 })
+
+	const n = netBusQueueLen + 500
+	key := make([]byte, 16<<10)
+	for i := 0; i < n; i++ {
+		if err := buses[0].Publish(key); err != nil {
+			t.Fatal("unexpected error ", err)
+		}
+	}
+	waitFor(t, func() bool { return received.Load() == n && s.Conns() == 2 })
+
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close must not block")
+	}
+}
+
+func TestNetBusCloseWhileDialing(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		s, err := NewNetBusServer("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("unexpected error ", err)
+		}
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// the connections are kept open, so the server must close
+				// the ones it accepts
+				var conns []net.Conn
+				defer func() {
+					for _, c := range conns {
+						c.Close()
+					}
+				}()
+				for len(conns) < 100 {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if c, err := net.Dial("tcp", s.Addr().String()); err == nil {
+						conns = append(conns, c)
+					}
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			s.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close must not block")
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,109 @@
+package container
+
+import (
+	"iter"
+)
+
+type (
+	// LruCursor allows to walk over Lru elements step by step. The current
+	// element can be deleted by the cursor Delete() method, what doesn't
+	// affect the walk. Any other modification of the container invalidates the
+	// cursor.
+	LruCursor[K comparable, V any] struct {
+		l       *TypedLru[K, V]
+		cur     *lru_element[K, V]
+		next    *lru_element[K, V]
+		left    int
+		reverse bool
+	}
+)
+
+// IterateReverse same as Iterate, but walks over the elements from the least
+// recently used to the most recently used one.
+//
+// Note: the modifications of the container must not allowed in the f
+func (l *TypedLru[K, V]) IterateReverse(f TypedLruCallback[K, V]) {
+	if l.head == nil {
+		return
+	}
+	t := l.head.prev
+	for {
+		if !f(t.v.key, t.v.val) || t == l.head {
+			break
+		}
+		t = t.prev
+	}
+}
+
+// All returns iterator over the elements in LRU order, from the most recently
+// used one.
+//
+// Note: the modifications of the container must not allowed in the loop body
+func (l *TypedLru[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		l.Iterate(TypedLruCallback[K, V](yield))
+	}
+}
+
+// Backward returns iterator over the elements from the least recently used
+// to the most recently used one.
+//
+// Note: the modifications of the container must not allowed in the loop body
+func (l *TypedLru[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		l.IterateReverse(TypedLruCallback[K, V](yield))
+	}
+}
+
+// Cursor returns the cursor which walks over the elements from the most
+// recently used one. Next() must be called to move to the first element.
+func (l *TypedLru[K, V]) Cursor() *LruCursor[K, V] {
+	return &LruCursor[K, V]{l: l, next: l.head, left: len(l.kvMap)}
+}
+
+// ReverseCursor returns the cursor which walks over the elements from the
+// least recently used one. Next() must be called to move to the first element.
+func (l *TypedLru[K, V]) ReverseCursor() *LruCursor[K, V] {
+	lc := &LruCursor[K, V]{l: l, left: len(l.kvMap), reverse: true}
+	if l.head != nil {
+		lc.next = l.head.prev
+	}
+	return lc
+}
+
+// Next moves the cursor to the next element. Returns false if there is no
+// elements to visit anymore.
+func (lc *LruCursor[K, V]) Next() bool {
+	if lc.left == 0 {
+		lc.cur = nil
+		return false
+	}
+	lc.left--
+	lc.cur = lc.next
+	if lc.reverse {
+		lc.next = lc.cur.prev
+	} else {
+		lc.next = lc.cur.next
+	}
+	return true
+}
+
+// Value returns the current element, or nil if the cursor is not moved to an
+// element yet, reached the end or the current element is deleted.
+func (lc *LruCursor[K, V]) Value() *TypedLruValue[K, V] {
+	if lc.cur == nil {
+		return nil
+	}
+	return &lc.cur.v
+}
+
+// Delete deletes the current element from the container and invokes the
+// delete callback for it. The cursor can be moved to the next element after
+// that.
+func (lc *LruCursor[K, V]) Delete() {
+	if lc.cur == nil {
+		return
+	}
+	lc.l.delete(lc.cur, LruDeleted, true)
+	lc.cur = nil
+}
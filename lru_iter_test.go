@@ -0,0 +1,116 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIterateReverse(t *testing.T) {
+	l := NewTypedLru[int, int](10, time.Hour, nil)
+	l.IterateReverse(func(k, v int) bool {
+		t.Fatal("must not be called for empty container")
+		return true
+	})
+
+	for i := 1; i <= 4; i++ {
+		l.Put(i, i, 1)
+	}
+	var keys []int
+	l.IterateReverse(func(k, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []int{1, 2, 3, 4}) {
+		t.Fatal("wrong order ", keys)
+	}
+
+	keys = keys[:0]
+	l.IterateReverse(func(k, v int) bool {
+		keys = append(keys, k)
+		return len(keys) < 2
+	})
+	if !reflect.DeepEqual(keys, []int{1, 2}) {
+		t.Fatal("wrong keys ", keys)
+	}
+}
+
+func TestAllBackward(t *testing.T) {
+	l := NewTypedLru[int, int](10, time.Hour, nil)
+	for i := 1; i <= 4; i++ {
+		l.Put(i, i*10, 1)
+	}
+
+	var keys []int
+	for k, v := range l.All() {
+		if v != k*10 {
+			t.Fatal("wrong value ", v, " for ", k)
+		}
+		keys = append(keys, k)
+		if k == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(keys, []int{4, 3, 2}) {
+		t.Fatal("wrong order ", keys)
+	}
+
+	keys = keys[:0]
+	for k := range l.Backward() {
+		keys = append(keys, k)
+	}
+	if !reflect.DeepEqual(keys, []int{1, 2, 3, 4}) {
+		t.Fatal("wrong order ", keys)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	var deleted []int
+	l := NewTypedLru(10, time.Hour, func(k, v int) {
+		deleted = append(deleted, k)
+	})
+	c := l.Cursor()
+	if c.Next() || c.Value() != nil {
+		t.Fatal("empty container cursor")
+	}
+
+	for i := 1; i <= 5; i++ {
+		l.Put(i, i, 1)
+	}
+
+	// evict oldest 2
+	c = l.ReverseCursor()
+	for i := 0; i < 2 && c.Next(); i++ {
+		c.Delete()
+		if c.Value() != nil {
+			t.Fatal("the deleted value must be nil")
+		}
+	}
+	if !reflect.DeepEqual(deleted, []int{1, 2}) || l.Len() != 3 {
+		t.Fatal("wrong deleted ", deleted)
+	}
+
+	// delete even keys walking from the head
+	var keys []int
+	c = l.Cursor()
+	for c.Next() {
+		keys = append(keys, c.Value().Key())
+		if c.Value().Key()%2 == 0 {
+			c.Delete()
+		}
+	}
+	if !reflect.DeepEqual(keys, []int{5, 4, 3}) || !reflect.DeepEqual(deleted, []int{1, 2, 4}) {
+		t.Fatal("wrong keys ", keys, " or deleted ", deleted)
+	}
+	if c.Next() || c.Value() != nil {
+		t.Fatal("the cursor must be at the end")
+	}
+
+	c = l.Cursor()
+	for c.Next() {
+		c.Delete()
+	}
+	if l.Len() != 0 || l.head != nil {
+		t.Fatal("must be empty")
+	}
+}
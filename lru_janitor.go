@@ -0,0 +1,69 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// LruJanitor runs the time sweep of a cache periodically in background, so
+	// expired elements are pulled out of the cache (and the delete callback
+	// is notified) even if the cache is not accessed for a long time.
+	LruJanitor struct {
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+)
+
+// StartLruJanitor starts new LruJanitor which calls sweep every interval
+// until the ctx is done or the janitor is closed.
+func StartLruJanitor(ctx context.Context, interval time.Duration, sweep func()) *LruJanitor {
+	if interval <= 0 {
+		panic("interval must be positive")
+	}
+	j := new(LruJanitor)
+	ctx, j.cancel = context.WithCancel(ctx)
+	j.done = make(chan struct{})
+	go j.run(ctx, interval, sweep)
+	return j
+}
+
+// StartJanitor starts LruJanitor for the container. Lru is not thread-safe,
+// so lock must be the one which protects the container from concurrent access.
+// The janitor holds the lock while sweeping, so the delete callback is invoked
+// the same way as for SweepByTime called under the lock.
+func (l *TypedLru[K, V]) StartJanitor(ctx context.Context, interval time.Duration, lock sync.Locker) *LruJanitor {
+	return StartLruJanitor(ctx, interval, func() {
+		lock.Lock()
+		l.SweepByTime()
+		lock.Unlock()
+	})
+}
+
+// StartJanitor starts LruJanitor which sweeps the container shards
+func (sl *ShardedLru[K, V]) StartJanitor(ctx context.Context, interval time.Duration) *LruJanitor {
+	return StartLruJanitor(ctx, interval, sl.SweepByTime)
+}
+
+// Close stops the janitor and waits until the current sweep is over. It is
+// safe to call Close several times.
+func (j *LruJanitor) Close() error {
+	j.cancel()
+	<-j.done
+	return nil
+}
+
+func (j *LruJanitor) run(ctx context.Context, interval time.Duration, sweep func()) {
+	defer close(j.done)
+	tckr := time.NewTicker(interval)
+	defer tckr.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tckr.C:
+			sweep()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLruJanitor(t *testing.T) {
+	var lock sync.Mutex
+	deleted := 0
+	l := NewTypedLru(100, 10*time.Millisecond, func(k, v int) {
+		deleted++
+	})
+	l.Put(1, 1, 1)
+	l.Put(2, 2, 1)
+
+	j := l.StartJanitor(context.Background(), time.Millisecond, &lock)
+	defer j.Close()
+
+	for i := 0; i < 100; i++ {
+		time.Sleep(5 * time.Millisecond)
+		lock.Lock()
+		ln, d := l.Len(), deleted
+		lock.Unlock()
+		if ln == 0 {
+			if d != 2 {
+				t.Fatal("expecting 2 deleted, but got ", d)
+			}
+			return
+		}
+	}
+	t.Fatal("the elements must be swept by the janitor")
+}
+
+func TestLruJanitorClose(t *testing.T) {
+	var lock sync.Mutex
+	l := NewTypedLru[int, int](100, 10*time.Millisecond, nil)
+	l.Put(1, 1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := l.StartJanitor(ctx, time.Millisecond, &lock)
+	cancel()
+	j.Close()
+	j.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if l.Len() != 1 {
+		t.Fatal("the janitor must be stopped")
+	}
+}
+
+func TestShardedLruJanitor(t *testing.T) {
+	l := NewShardedLru[int, int](4, 100, 10*time.Millisecond, nil)
+	for i := 0; i < 10; i++ {
+		l.Put(i, i, 1)
+	}
+
+	j := l.StartJanitor(context.Background(), time.Millisecond)
+	defer j.Close()
+
+	for i := 0; i < 100; i++ {
+		time.Sleep(5 * time.Millisecond)
+		if l.Len() == 0 {
+			return
+		}
+	}
+	t.Fatal("the elements must be swept by the janitor")
+}
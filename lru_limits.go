@@ -0,0 +1,90 @@
+package container
+
+import (
+	"time"
+)
+
+type (
+	// LruLimits describes the container limits
+	LruLimits struct {
+		// MaxSize is the maximum summary size of the elements
+		MaxSize int64
+		// MaxDuration is the maximum time an element can stay in the cache
+		// without being touched, 0 means it is not limited
+		MaxDuration time.Duration
+	}
+)
+
+// Limits returns current limits of the container
+func (l *TypedLru[K, V]) Limits() LruLimits {
+	return LruLimits{MaxSize: l.maxSize, MaxDuration: l.maxDur}
+}
+
+// SetMaxSize changes the maximum size of the container. If the current size
+// exceeds the new maximum, the least recently used elements are pulled out
+// immediately and the delete callback is invoked for them.
+func (l *TypedLru[K, V]) SetMaxSize(maxSize int64) {
+	l.maxSize = maxSize
+	l.sweepBySize(0)
+}
+
+// SetMaxDuration changes the maximum time an element can stay in the cache
+// without being touched. The expired elements are pulled out immediately and
+// the delete callback is invoked for them. If the time was not controlled
+// before (it was 0), all the elements are considered as touched now.
+func (l *TypedLru[K, V]) SetMaxDuration(to time.Duration) {
+	if l.maxDur == 0 && to > 0 {
+		tm := l.clockNow()
+		for e := l.head; e != nil; {
+			e.v.ts = tm
+			e = e.next
+			if e == l.head {
+				break
+			}
+		}
+	}
+	l.maxDur = to
+	l.SweepByTime()
+}
+
+// Limits returns current limits of the container, MaxSize is the total size
+// of all shards
+func (sl *ShardedLru[K, V]) Limits() LruLimits {
+	var res LruLimits
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		lim := s.lru.Limits()
+		s.lock.Unlock()
+		res.MaxSize += lim.MaxSize
+		res.MaxDuration = lim.MaxDuration
+	}
+	return res
+}
+
+// SetMaxSize changes the total maximum size of the shards, it is split between
+// the shards evenly. See Lru.SetMaxSize
+func (sl *ShardedLru[K, V]) SetMaxSize(maxSize int64) {
+	n := int64(len(sl.shards))
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		sz := maxSize / n
+		if int64(i) < maxSize%n {
+			sz++
+		}
+		s.lock.Lock()
+		s.lru.SetMaxSize(sz)
+		sl.unlock(s)
+	}
+}
+
+// SetMaxDuration changes the maximum time an element can stay in the cache
+// without being touched for all shards. See Lru.SetMaxDuration
+func (sl *ShardedLru[K, V]) SetMaxDuration(to time.Duration) {
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		s.lru.SetMaxDuration(to)
+		sl.unlock(s)
+	}
+}
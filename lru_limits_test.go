@@ -0,0 +1,75 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLruSetMaxSize(t *testing.T) {
+	var deleted []int
+	l := NewTypedLru(10, time.Hour, func(k, v int) {
+		deleted = append(deleted, k)
+	})
+	for i := 0; i < 10; i++ {
+		l.Put(i, i, 1)
+	}
+
+	l.SetMaxSize(7)
+	if l.Size() != 7 || !reflect.DeepEqual(deleted, []int{0, 1, 2}) {
+		t.Fatal("expecting size 7, but it is ", l.Size(), " deleted=", deleted)
+	}
+	if l.Limits() != (LruLimits{MaxSize: 7, MaxDuration: time.Hour}) {
+		t.Fatal("wrong limits ", l.Limits())
+	}
+
+	l.SetMaxSize(20)
+	for i := 0; i < 20; i++ {
+		l.Put(i+100, i, 1)
+	}
+	if l.Size() != 20 {
+		t.Fatal("expecting size 20, but it is ", l.Size())
+	}
+}
+
+func TestLruSetMaxDuration(t *testing.T) {
+	now := time.Now()
+	deleted := 0
+	l := NewLruWithClock(10, 0, func(k, v int) {
+		deleted++
+	}, func() time.Time { return now })
+	l.Put(1, 1, 1)
+	now = now.Add(time.Hour)
+	l.Put(2, 2, 1)
+
+	// the elements are considered as touched now
+	l.SetMaxDuration(time.Minute)
+	if l.Len() != 2 || deleted != 0 {
+		t.Fatal("no elements must be expired")
+	}
+
+	now = now.Add(30 * time.Second)
+	l.Get(2)
+	l.SetMaxDuration(20 * time.Second)
+	if l.Len() != 1 || deleted != 1 || l.Peek(2) == nil {
+		t.Fatal("1 must be expired")
+	}
+
+	l.SetMaxDuration(0)
+	now = now.Add(time.Hour)
+	if l.Len() != 1 || l.Limits().MaxDuration != 0 {
+		t.Fatal("time must not be controlled")
+	}
+}
+
+func TestShardedLruLimits(t *testing.T) {
+	l := NewShardedLru[int, int](4, 100, time.Hour, nil)
+	for i := 0; i < 100; i++ {
+		l.Put(i, i, 1)
+	}
+	l.SetMaxSize(10)
+	l.SetMaxDuration(time.Minute)
+	if l.Size() > 10 || l.Limits() != (LruLimits{MaxSize: 10, MaxDuration: time.Minute}) {
+		t.Fatal("wrong size ", l.Size(), " or limits ", l.Limits())
+	}
+}
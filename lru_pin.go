@@ -0,0 +1,51 @@
+package container
+
+type (
+	// LruHandle is the reference to a container element returned by Acquire.
+	// The element is not pulled out of the cache by size or time while it is
+	// referenced. If the element is deleted explicitly, replaced or cleared,
+	// it disappears from the container, but the delete callback is invoked
+	// only when the last reference to the element is released.
+	LruHandle[K comparable, V any] struct {
+		e *lru_element[K, V]
+	}
+)
+
+// Acquire same as Get, but it also pins the element, so it is not pulled out
+// of the cache until the handle is released. Returns nil if there is no such
+// key. Every handle must be released by Release.
+func (l *TypedLru[K, V]) Acquire(k K) *LruHandle[K, V] {
+	ts := l.SweepByTime()
+	if l.get(k, ts) == nil {
+		return nil
+	}
+	e := l.kvMap[k]
+	e.refs++
+	return &LruHandle[K, V]{e: e}
+}
+
+// Release releases the handle returned by Acquire. If the element was deleted
+// from the container while it was referenced, and this is the last reference,
+// the delete callback is invoked. It is safe to release the handle several
+// times, the handle cannot be used after it is released.
+func (l *TypedLru[K, V]) Release(h *LruHandle[K, V]) {
+	if h == nil || h.e == nil {
+		return
+	}
+	e := h.e
+	h.e = nil
+	e.refs--
+	if e.refs == 0 && e.zombie {
+		e.zombie = false
+		l.dispose(e, e.zr, e.zcb)
+	}
+}
+
+// Value returns the element the handle references, or nil if the handle is
+// released
+func (h *LruHandle[K, V]) Value() *TypedLruValue[K, V] {
+	if h.e == nil {
+		return nil
+	}
+	return &h.e.v
+}
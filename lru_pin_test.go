@@ -0,0 +1,125 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAcquireSize(t *testing.T) {
+	var deleted []int
+	l := NewTypedLru(3, time.Hour, func(k, v int) {
+		deleted = append(deleted, k)
+	})
+	if l.Acquire(1) != nil {
+		t.Fatal("no such key")
+	}
+	l.Put(1, 1, 1)
+	h := l.Acquire(1)
+	l.Put(2, 2, 1)
+	l.Put(3, 3, 1)
+
+	// 1 is the least recently used, but it is pinned
+	l.Put(4, 4, 1)
+	if !reflect.DeepEqual(deleted, []int{2}) || l.Peek(1) == nil {
+		t.Fatal("2 must be evicted, but deleted=", deleted)
+	}
+	if h.Value().Val() != 1 {
+		t.Fatal("wrong value")
+	}
+
+	l.Release(h)
+	l.Release(h)
+	if h.Value() != nil {
+		t.Fatal("the handle is released")
+	}
+	l.Put(5, 5, 1)
+	if !reflect.DeepEqual(deleted, []int{2, 1}) {
+		t.Fatal("1 must be evicted, but deleted=", deleted)
+	}
+
+	// all pinned
+	h3, h4, h5 := l.Acquire(3), l.Acquire(4), l.Acquire(5)
+	l.Put(6, 6, 1)
+	if l.Len() != 4 || l.Size() != 4 {
+		t.Fatal("the pinned elements must not be evicted")
+	}
+	l.Release(h3)
+	l.Release(h4)
+	l.Release(h5)
+}
+
+func TestAcquireTime(t *testing.T) {
+	now := time.Now()
+	var deleted []int
+	l := NewLruWithClock(10, time.Second, func(k, v int) {
+		deleted = append(deleted, k)
+	}, func() time.Time { return now })
+	l.Put(1, 1, 1)
+	l.PutWithTTL(2, 2, 1, 100*time.Millisecond)
+	l.PutWithTTL(3, 3, 1, 200*time.Millisecond)
+	h := l.Acquire(1)
+	h2 := l.Acquire(2)
+
+	now = now.Add(2 * time.Second)
+	l.SweepByTime()
+	if !reflect.DeepEqual(deleted, []int{3}) || l.Len() != 2 || len(l.ttlHeap) != 1 {
+		t.Fatal("only 3 must be expired, but deleted=", deleted)
+	}
+
+	l.Release(h)
+	l.Release(h2)
+	l.SweepByTime()
+	if !reflect.DeepEqual(deleted, []int{3, 2, 1}) && !reflect.DeepEqual(deleted, []int{3, 1, 2}) {
+		t.Fatal("all must be expired, but deleted=", deleted)
+	}
+	if l.Len() != 0 || len(l.ttlHeap) != 0 {
+		t.Fatal("must be empty")
+	}
+}
+
+func TestAcquireDelete(t *testing.T) {
+	var deleted []int
+	var reasons []LruEvictReason
+	l := NewTypedLru(10, time.Hour, func(k, v int) {
+		deleted = append(deleted, v)
+	})
+	l.SetEvictCallback(func(k, v int, size int64, r LruEvictReason) {
+		reasons = append(reasons, r)
+	})
+	l.Put(1, 1, 1)
+	h1 := l.Acquire(1)
+	h2 := l.Acquire(1)
+
+	// replaced, but the old value is still referenced
+	l.Put(1, 11, 1)
+	if l.Peek(1).Val() != 11 || h1.Value().Val() != 1 || len(deleted) != 0 {
+		t.Fatal("the old value must be referenced")
+	}
+	l.Release(h1)
+	if len(deleted) != 0 {
+		t.Fatal("the callback must not be invoked before the last release")
+	}
+	l.Release(h2)
+	if !reflect.DeepEqual(deleted, []int{1}) || !reflect.DeepEqual(reasons, []LruEvictReason{LruReplaced}) {
+		t.Fatal("the callback must be invoked once, deleted=", deleted)
+	}
+
+	h := l.Acquire(1)
+	l.Delete(1)
+	if l.Len() != 0 || l.Size() != 0 || len(deleted) != 1 {
+		t.Fatal("1 must be deleted from the container")
+	}
+	l.Put(1, 111, 1)
+	l.Release(h)
+	if !reflect.DeepEqual(deleted, []int{1, 11}) || l.Peek(1).Val() != 111 {
+		t.Fatal("wrong deleted=", deleted)
+	}
+
+	h = l.Acquire(1)
+	l.Clear(false)
+	l.Release(h)
+	if len(deleted) != 2 {
+		t.Fatal("the callback must not be invoked for Clear(false)")
+	}
+}
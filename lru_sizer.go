@@ -0,0 +1,65 @@
+package container
+
+import (
+	"time"
+)
+
+type (
+	// LruSizer is implemented by the values which know their size
+	LruSizer interface {
+		Size() int64
+	}
+
+	// LruSizeF is a function which calculates size of the key-value pair
+	LruSizeF[K comparable, V any] func(k K, v V) int64
+)
+
+// DefaultLruSize returns the key-value pair size. Size of a string or a slice of
+// bytes is its length, size of a value which implements LruSizer is the
+// value of its Size() method. Both key and value sizes are counted. If
+// neither key nor value size can be calculated, the pair size is 1.
+func DefaultLruSize[K comparable, V any](k K, v V) int64 {
+	ks, kok := sizeOf(k)
+	vs, vok := sizeOf(v)
+	if !kok && !vok {
+		return 1
+	}
+	return ks + vs
+}
+
+func sizeOf(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case string:
+		return int64(len(t)), true
+	case []byte:
+		return int64(len(t)), true
+	case LruSizer:
+		return t.Size(), true
+	}
+	return 0, false
+}
+
+// SetSizeFunc sets the function which calculates size of the elements put by
+// PutAuto. If the sizeF is nil, DefaultLruSize is used.
+func (l *TypedLru[K, V]) SetSizeFunc(sizeF LruSizeF[K, V]) {
+	l.sizeF = sizeF
+}
+
+// PutAuto same as Put, but the element size is calculated by the function
+// set by SetSizeFunc, or by DefaultLruSize
+func (l *TypedLru[K, V]) PutAuto(k K, v V) {
+	l.Put(k, v, l.sizeOf(k, v))
+}
+
+// PutAutoWithTTL same as PutWithTTL, but the element size is calculated the
+// same way as for PutAuto
+func (l *TypedLru[K, V]) PutAutoWithTTL(k K, v V, ttl time.Duration) {
+	l.PutWithTTL(k, v, l.sizeOf(k, v), ttl)
+}
+
+func (l *TypedLru[K, V]) sizeOf(k K, v V) int64 {
+	if l.sizeF != nil {
+		return l.sizeF(k, v)
+	}
+	return DefaultLruSize(k, v)
+}
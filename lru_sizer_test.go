@@ -0,0 +1,55 @@
+package container
+
+import (
+	"testing"
+	"time"
+)
+
+type testSized struct {
+	sz int64
+}
+
+func (ts testSized) Size() int64 {
+	return ts.sz
+}
+
+func TestDefaultLruSize(t *testing.T) {
+	if DefaultLruSize("abc", []byte("de")) != 5 {
+		t.Fatal("expecting 5")
+	}
+	if DefaultLruSize(1, "abcd") != 4 || DefaultLruSize("ab", 1) != 2 {
+		t.Fatal("expecting 4 and 2")
+	}
+	if DefaultLruSize[int, LruSizer](1, testSized{42}) != 42 || DefaultLruSize(1, testSized{42}) != 42 {
+		t.Fatal("expecting 42")
+	}
+	if DefaultLruSize(1, 2.0) != 1 {
+		t.Fatal("expecting 1 for unknown types")
+	}
+	var sz LruSizer
+	if DefaultLruSize(1, sz) != 1 {
+		t.Fatal("expecting 1 for nil interface")
+	}
+}
+
+func TestPutAuto(t *testing.T) {
+	l := NewTypedLru[string, []byte](10, time.Hour, nil)
+	l.PutAuto("a", []byte("1234"))
+	l.PutAuto("b", []byte("123"))
+	if l.Size() != 9 || l.Peek("a").Size() != 5 {
+		t.Fatal("expecting size 9, but it is ", l.Size())
+	}
+	l.PutAutoWithTTL("c", []byte("1"), time.Minute)
+	if l.Len() != 2 || l.Peek("a") != nil || l.Peek("c").ExpiresAt().IsZero() {
+		t.Fatal("a must be evicted")
+	}
+
+	l.SetSizeFunc(func(k string, v []byte) int64 {
+		return int64(len(v)) + 100
+	})
+	l.SetMaxSize(1000)
+	l.PutAuto("d", nil)
+	if l.Peek("d").Size() != 100 {
+		t.Fatal("expecting size 100, but it is ", l.Peek("d").Size())
+	}
+}
@@ -0,0 +1,241 @@
+package container
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kplr-io/container/btsbuf"
+)
+
+type (
+	// LruCodec allows to encode values of type T to slice of bytes and decode
+	// them back. It is used for storing the Lru snapshots.
+	LruCodec[T any] interface {
+		Encode(v T) ([]byte, error)
+		Decode(buf []byte) (T, error)
+	}
+
+	// LruStringCodec implements LruCodec for strings
+	LruStringCodec struct{}
+
+	// LruBytesCodec implements LruCodec for slices of bytes
+	LruBytesCodec struct{}
+
+	// lru_record is an element stored in the snapshot, or on the disk
+	lru_record struct {
+		size int64
+		ts   time.Time
+		exp  time.Time
+		key  []byte
+		val  []byte
+	}
+)
+
+const (
+	// lruSnapshotHdrSize is the size of the record header: size, touch time
+	// and own deadline of an element
+	lruSnapshotHdrSize = 24
+	lruSnapshotEOF     = 0xFFFFFFFF
+	// lruSnapshotMaxRecord is the maximum record length, it protects Restore
+	// from huge allocations when the snapshot is corrupted
+	lruSnapshotMaxRecord = 1 << 26
+)
+
+// Snapshot writes the container elements to w in LRU order. Every element is
+// written as a record which contains its key and value encoded by kc and vc,
+// its size, TouchedAt and ExpiresAt times. The record is btsbuf chunks
+// (header, key and value) prefixed by their total length. The snapshot is
+// terminated by 0xFFFFFFFF marker. The encoded element must not exceed 64MiB.
+func (l *TypedLru[K, V]) Snapshot(w io.Writer, kc LruCodec[K], vc LruCodec[V]) error {
+	var bbw btsbuf.Writer
+	h := l.head
+	for h != nil {
+		kb, err := kc.Encode(h.v.key)
+		if err != nil {
+			return fmt.Errorf("could not encode key %v: %w", h.v.key, err)
+		}
+		vb, err := vc.Encode(h.v.val)
+		if err != nil {
+			return fmt.Errorf("could not encode value for key %v: %w", h.v.key, err)
+		}
+
+		rec := encodeLruRecord(&bbw, lru_record{size: h.v.size, ts: h.v.ts, exp: h.v.exp, key: kb, val: vb})
+		if len(rec) > lruSnapshotMaxRecord {
+			return fmt.Errorf("the record for key %v is too big: %d bytes", h.v.key, len(rec))
+		}
+
+		var ln [4]byte
+		binary.BigEndian.PutUint32(ln[:], uint32(len(rec)))
+		if _, err := w.Write(ln[:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+
+		h = h.next
+		if h == l.head {
+			break
+		}
+	}
+
+	var eof [4]byte
+	binary.BigEndian.PutUint32(eof[:], lruSnapshotEOF)
+	_, err := w.Write(eof[:])
+	return err
+}
+
+// Restore reads the snapshot written by Snapshot from r and places the
+// elements into the container preserving their order, touch times and own
+// deadlines. The restored elements are placed after the elements, which are
+// already in the container, and the keys which are already in the container
+// are skipped. The expired elements and the elements which don't fit into
+// maxSize are dropped without invoking the delete callback. Restore returns
+// number of the restored elements.
+func (l *TypedLru[K, V]) Restore(r io.Reader, kc LruCodec[K], vc LruCodec[V]) (int, error) {
+	tm := l.SweepByTime()
+	if tm.IsZero() {
+		tm = l.clockNow()
+	}
+	var bbr btsbuf.Reader
+	var ln [4]byte
+	var buf []byte
+	cnt := 0
+	for {
+		if _, err := io.ReadFull(r, ln[:]); err != nil {
+			if err == io.EOF {
+				return cnt, nil
+			}
+			return cnt, err
+		}
+		n := binary.BigEndian.Uint32(ln[:])
+		if n == lruSnapshotEOF {
+			return cnt, nil
+		}
+		if n > lruSnapshotMaxRecord {
+			return cnt, fmt.Errorf("the record is too big: %d bytes", n)
+		}
+		if cap(buf) < int(n) {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return cnt, err
+		}
+
+		rec, err := decodeLruRecord(&bbr, buf)
+		if err != nil {
+			return cnt, err
+		}
+		k, err := kc.Decode(rec.key)
+		if err != nil {
+			return cnt, fmt.Errorf("could not decode key: %w", err)
+		}
+
+		if _, ok := l.kvMap[k]; ok || l.size+rec.size > l.maxSize {
+			continue
+		}
+		if l.maxDur > 0 && tm.Sub(rec.ts) > l.maxDur || !rec.exp.IsZero() && tm.After(rec.exp) {
+			continue
+		}
+
+		v, err := vc.Decode(rec.val)
+		if err != nil {
+			return cnt, fmt.Errorf("could not decode value for key %v: %w", k, err)
+		}
+		l.restore(k, v, rec.size, rec.ts, rec.exp)
+		cnt++
+	}
+}
+
+// restore adds the element to the tail of the list
+func (l *TypedLru[K, V]) restore(k K, v V, size int64, ts, exp time.Time) {
+	e := l.newElement()
+	e.v.key = k
+	e.v.val = v
+	e.v.ts = ts
+	e.v.size = size
+	e.v.exp = exp
+	e.hidx = -1
+	if !exp.IsZero() {
+		heap.Push(&l.ttlHeap, e)
+	}
+	h := addToHead(l.head, e)
+	if l.head == nil {
+		l.head = h
+	}
+	l.kvMap[k] = e
+	l.size += size
+}
+
+// encodeLruRecord writes the record as btsbuf chunks (header, key and value)
+// using bbw and returns the written chunks. The result is valid until the next
+// bbw use.
+func encodeLruRecord(bbw *btsbuf.Writer, rec lru_record) []byte {
+	bbw.Reset(bbw.Buf(), true)
+	hdr, _ := bbw.Allocate(lruSnapshotHdrSize, true)
+	binary.BigEndian.PutUint64(hdr, uint64(rec.size))
+	binary.BigEndian.PutUint64(hdr[8:], timeToUint64(rec.ts))
+	binary.BigEndian.PutUint64(hdr[16:], timeToUint64(rec.exp))
+	kbuf, _ := bbw.Allocate(len(rec.key), true)
+	copy(kbuf, rec.key)
+	vbuf, _ := bbw.Allocate(len(rec.val), true)
+	copy(vbuf, rec.val)
+	res, _ := bbw.Close()
+	return res
+}
+
+// decodeLruRecord reads the record written by encodeLruRecord, the key and
+// the value of the returned record point to buf
+func decodeLruRecord(bbr *btsbuf.Reader, buf []byte) (lru_record, error) {
+	var rec lru_record
+	if err := bbr.Reset(buf); err != nil || bbr.Len() != 3 || len(bbr.Get()) != lruSnapshotHdrSize {
+		return rec, errors.New("broken record")
+	}
+	hdr := bbr.Get()
+	rec.size = int64(binary.BigEndian.Uint64(hdr))
+	rec.ts = uint64ToTime(binary.BigEndian.Uint64(hdr[8:]))
+	rec.exp = uint64ToTime(binary.BigEndian.Uint64(hdr[16:]))
+	bbr.Next()
+	rec.key = bbr.Get()
+	bbr.Next()
+	rec.val = bbr.Get()
+	return rec, nil
+}
+
+func timeToUint64(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func uint64ToTime(v uint64) time.Time {
+	if v == 0 {
+		return nilTime
+	}
+	return time.Unix(0, int64(v))
+}
+
+func (LruStringCodec) Encode(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (LruStringCodec) Decode(buf []byte) (string, error) {
+	return string(buf), nil
+}
+
+func (LruBytesCodec) Encode(v []byte) ([]byte, error) {
+	return v, nil
+}
+
+// Decode returns a copy of buf
+func (LruBytesCodec) Decode(buf []byte) ([]byte, error) {
+	res := make([]byte, len(buf))
+	copy(res, buf)
+	return res, nil
+}
@@ -0,0 +1,122 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type testIntCodec struct{}
+
+func (testIntCodec) Encode(v int) ([]byte, error) {
+	if v < 0 {
+		return nil, errors.New("negative")
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	return b[:], nil
+}
+
+func (testIntCodec) Decode(buf []byte) (int, error) {
+	if len(buf) != 8 {
+		return 0, errors.New("wrong length")
+	}
+	return int(binary.BigEndian.Uint64(buf)), nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	now := time.Now()
+	clck := func() time.Time { return now }
+	l := NewLruWithClock[string, int](100, time.Hour, nil, clck)
+	l.Put("a", 1, 10)
+	now = now.Add(time.Minute)
+	l.Put("b", 2, 20)
+	l.PutWithTTL("c", 3, 30, time.Minute)
+	now = now.Add(30 * time.Second)
+	l.Get("a")
+
+	var buf bytes.Buffer
+	if err := l.Snapshot(&buf, LruStringCodec{}, testIntCodec{}); err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+
+	l2 := NewLruWithClock[string, int](100, time.Hour, nil, clck)
+	n, err := l2.Restore(bytes.NewReader(buf.Bytes()), LruStringCodec{}, testIntCodec{})
+	if err != nil || n != 3 || l2.Size() != 60 {
+		t.Fatal("expecting 3 restored elements, but n=", n, ", err=", err)
+	}
+
+	var keys []string
+	l2.Iterate(func(k string, v int) bool {
+		keys = append(keys, k)
+		if l.Peek(k).Val() != v || !l.Peek(k).TouchedAt().Equal(l2.Peek(k).TouchedAt()) {
+			t.Fatal("wrong value for ", k)
+		}
+		return true
+	})
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "c" || keys[2] != "b" {
+		t.Fatal("wrong order ", keys)
+	}
+	if !l2.Peek("c").ExpiresAt().Equal(l.Peek("c").ExpiresAt()) || len(l2.ttlHeap) != 1 {
+		t.Fatal("c must keep own deadline")
+	}
+
+	// b is the least recently used and doesn't fit, c is expired
+	now = now.Add(31 * time.Second)
+	l3 := NewLruWithClock[string, int](25, time.Hour, nil, clck)
+	n, err = l3.Restore(bytes.NewReader(buf.Bytes()), LruStringCodec{}, testIntCodec{})
+	if err != nil || n != 1 || l3.Peek("a") == nil {
+		t.Fatal("expecting only a restored, but n=", n, ", err=", err)
+	}
+
+	// b is expired
+	now = now.Add(time.Hour - 50*time.Second)
+	l4 := NewLruWithClock[string, int](100, time.Hour, nil, clck)
+	n, err = l4.Restore(bytes.NewReader(buf.Bytes()), LruStringCodec{}, testIntCodec{})
+	if err != nil || n != 1 || l4.Peek("a") == nil {
+		t.Fatal("expecting only a restored, but n=", n, ", err=", err)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	l := NewTypedLru[string, int](100, 0, nil)
+	l.Put("a", -1, 1)
+	var buf bytes.Buffer
+	if err := l.Snapshot(&buf, LruStringCodec{}, testIntCodec{}); err == nil {
+		t.Fatal("expecting encoding error")
+	}
+
+	l.Put("a", 1, 1)
+	buf.Reset()
+	l.Snapshot(&buf, LruStringCodec{}, testIntCodec{})
+	b := buf.Bytes()
+	if _, err := l.Restore(bytes.NewReader(b[:len(b)-6]), LruStringCodec{}, testIntCodec{}); err == nil {
+		t.Fatal("expecting error for truncated snapshot")
+	}
+
+	// the header chunk length
+	b[5] = 0xFF
+	l2 := NewTypedLru[string, int](100, 0, nil)
+	if _, err := l2.Restore(bytes.NewReader(b), LruStringCodec{}, testIntCodec{}); err == nil {
+		t.Fatal("expecting error for broken snapshot")
+	}
+
+	// the corrupted record length must not cause the huge allocation
+	var ms0, ms1 runtime.MemStats
+	runtime.ReadMemStats(&ms0)
+	if _, err := l2.Restore(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xF0, 1}), LruStringCodec{}, testIntCodec{}); err == nil {
+		t.Fatal("expecting error for the huge record")
+	}
+	runtime.ReadMemStats(&ms1)
+	if ms1.TotalAlloc-ms0.TotalAlloc > 1<<20 {
+		t.Fatal("expecting no buffer allocation, but allocated ", ms1.TotalAlloc-ms0.TotalAlloc)
+	}
+
+	n, err := l2.Restore(bytes.NewReader(nil), LruStringCodec{}, testIntCodec{})
+	if n != 0 || err != nil {
+		t.Fatal("empty reader must be ok")
+	}
+}
@@ -0,0 +1,85 @@
+package container
+
+type (
+	// LruStats is the snapshot of the Lru counters
+	LruStats struct {
+		// Hits is the number of Get calls which found the key
+		Hits int64
+		// Misses is the number of Get calls which didn't find the key
+		Misses int64
+		// Puts is the number of Put calls
+		Puts int64
+		// Replacements is the number of Put calls which replaced existing key
+		Replacements int64
+		// EvictedBySize is the number of elements pulled out to free space
+		EvictedBySize int64
+		// EvictedByTime is the number of expired elements
+		EvictedByTime int64
+		// Deletes is the number of elements deleted explicitly
+		Deletes int64
+	}
+)
+
+// Stats returns the snapshot of the container counters
+func (l *TypedLru[K, V]) Stats() LruStats {
+	return l.stats
+}
+
+// ResetStats drops all the container counters to 0
+func (l *TypedLru[K, V]) ResetStats() {
+	l.stats = LruStats{}
+}
+
+// Stats returns the sum of the shards counters
+func (sl *ShardedLru[K, V]) Stats() LruStats {
+	var res LruStats
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		res.Add(s.lru.Stats())
+		s.lock.Unlock()
+	}
+	return res
+}
+
+// ResetStats drops counters of all shards to 0
+func (sl *ShardedLru[K, V]) ResetStats() {
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		s.lru.ResetStats()
+		s.lock.Unlock()
+	}
+}
+
+// HitRatio returns Hits/(Hits+Misses) or 0 if there were no Get calls
+func (ls LruStats) HitRatio() float64 {
+	if ls.Hits+ls.Misses == 0 {
+		return 0
+	}
+	return float64(ls.Hits) / float64(ls.Hits+ls.Misses)
+}
+
+// Add adds the other counters to ls
+func (ls *LruStats) Add(other LruStats) {
+	ls.Hits += other.Hits
+	ls.Misses += other.Misses
+	ls.Puts += other.Puts
+	ls.Replacements += other.Replacements
+	ls.EvictedBySize += other.EvictedBySize
+	ls.EvictedByTime += other.EvictedByTime
+	ls.Deletes += other.Deletes
+}
+
+func (ls *LruStats) count(r LruEvictReason) {
+	switch r {
+	case LruExpired:
+		ls.EvictedByTime++
+	case LruCapacity:
+		ls.EvictedBySize++
+	case LruReplaced:
+		ls.Replacements++
+	case LruDeleted:
+		ls.Deletes++
+	}
+}
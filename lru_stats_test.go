@@ -0,0 +1,55 @@
+package container
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLruStats(t *testing.T) {
+	now := time.Now()
+	l := NewLruWithClock[int, int](3, time.Second, nil, func() time.Time { return now })
+	l.Put(1, 1, 1)
+	l.Put(1, 1, 1)
+	l.Put(2, 2, 1)
+	l.Put(3, 3, 2)
+	l.Get(1)
+	l.Get(3)
+	l.Peek(2)
+	l.Delete(3)
+	l.Put(4, 4, 1)
+	l.DeleteNoCallback(4)
+	l.Put(5, 5, 1)
+	now = now.Add(2 * time.Second)
+	l.Get(5)
+	l.Clear(true)
+
+	exp := LruStats{Hits: 1, Misses: 2, Puts: 6, Replacements: 1, EvictedBySize: 1, EvictedByTime: 2, Deletes: 2}
+	if l.Stats() != exp {
+		t.Fatal("expecting ", exp, ", but got ", l.Stats())
+	}
+	if l.Stats().HitRatio() != float64(1)/3 {
+		t.Fatal("wrong hit ratio ", l.Stats().HitRatio())
+	}
+
+	l.ResetStats()
+	if l.Stats() != (LruStats{}) || l.Stats().HitRatio() != 0 {
+		t.Fatal("the stats must be reset")
+	}
+}
+
+func TestShardedLruStats(t *testing.T) {
+	l := NewShardedLru[int, int](4, 100, time.Hour, nil)
+	for i := 0; i < 10; i++ {
+		l.Put(i, i, 1)
+		l.Get(i)
+		l.Get(i + 100)
+	}
+	st := l.Stats()
+	if st.Puts != 10 || st.Hits != 10 || st.Misses != 10 {
+		t.Fatal("wrong stats ", st)
+	}
+	l.ResetStats()
+	if l.Stats() != (LruStats{}) {
+		t.Fatal("the stats must be reset")
+	}
+}
@@ -2,6 +2,7 @@ package container
 
 import (
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -16,6 +17,46 @@ func BenchmarkLocal(b *testing.B) {
 	}
 }
 
+func BenchmarkLocalPool(b *testing.B) {
+	l := NewLru(1000, time.Second, nil)
+	l.SetPoolSize(1000)
+	rand.Seed(time.Now().UTC().UnixNano())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Put(rand.Intn(1000), rand.Intn(150), 1)
+		l.Get(rand.Intn(1000))
+	}
+}
+
+// benchmarkBursts deletes and puts back bursts of 100 elements
+func benchmarkBursts(b *testing.B, poolSize int) {
+	l := NewTypedLru[int, int](1000, 0, nil)
+	l.SetPoolSize(poolSize)
+	for i := 0; i < 1000; i++ {
+		l.Put(i, i, 1)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st := (i * 100) % 1000
+		for j := st; j < st+100; j++ {
+			l.Delete(j)
+		}
+		for j := st; j < st+100; j++ {
+			l.Put(j, j, 1)
+		}
+	}
+}
+
+func BenchmarkBursts(b *testing.B) {
+	benchmarkBursts(b, 1)
+}
+
+func BenchmarkBurstsPool(b *testing.B) {
+	benchmarkBursts(b, 100)
+}
+
 func TestSimple(t *testing.T) {
 	l := NewLru(1000, time.Hour, nil)
 	l.Put("a", 23, 100)
@@ -100,14 +141,15 @@ func TestDelete(t *testing.T) {
 }
 
 func TestTimeout(t *testing.T) {
-	l := NewLru(1000, time.Millisecond*10, nil)
+	now := time.Now()
+	l := NewLruWithClock[interface{}, interface{}](1000, time.Millisecond*10, nil, func() time.Time { return now })
 	l.Put(1, 1, 1)
 	l.Put(2, 2, 1)
 	if l.Len() != 2 {
 		t.Fatal("Must have 2 elements")
 	}
 
-	time.Sleep(10 * time.Millisecond)
+	now = now.Add(11 * time.Millisecond)
 	l.SweepByTime()
 	if l.Len() != 0 || l.Get(1) != nil || l.Get(2) != nil {
 		t.Fatal("Must have 0 elements")
@@ -161,12 +203,12 @@ func TestDeleteOrder2(t *testing.T) {
 }
 
 func TestAddToList(t *testing.T) {
-	h := addToHead(nil, nil)
+	h := addToHead[interface{}, interface{}](nil, nil)
 	if h != nil {
 		t.Fatal("Wrong nil, nil adding result")
 	}
 
-	e := new(lru_element)
+	e := new(lru_element[interface{}, interface{}])
 	h = addToHead(nil, e)
 	if h != e || h.next != e || h.prev != e {
 		t.Fatal("Incorrect list")
@@ -177,7 +219,7 @@ func TestAddToList(t *testing.T) {
 		t.Fatal("Incorrect list (2)")
 	}
 
-	e1 := new(lru_element)
+	e1 := new(lru_element[interface{}, interface{}])
 	h = addToHead(h, e1)
 	if h != e1 || h.next != e || h.prev != e || e.prev != h || e.next != h {
 		t.Fatal("Incorrect list (3)")
@@ -185,9 +227,9 @@ func TestAddToList(t *testing.T) {
 }
 
 func TestRemoveFromList(t *testing.T) {
-	e3 := new(lru_element)
-	e2 := new(lru_element)
-	e1 := new(lru_element)
+	e3 := new(lru_element[interface{}, interface{}])
+	e2 := new(lru_element[interface{}, interface{}])
+	e1 := new(lru_element[interface{}, interface{}])
 	h := addToHead(nil, e3)
 	h = addToHead(h, e2)
 	h = addToHead(h, e1)
@@ -293,3 +335,219 @@ func TestClear(t *testing.T) {
 	}
 
 }
+
+func TestUntypedNames(t *testing.T) {
+	var deleted interface{}
+	var cb LruDeleteCallback = func(k, v interface{}) {
+		deleted = k
+	}
+	var l *Lru = NewLru(1, 0, cb)
+	l.Put("a", 1, 1)
+	var v *LruValue = l.Get("a")
+	if v == nil || v.Val().(int) != 1 {
+		t.Fatal("expecting 1, but ", v)
+	}
+
+	var f LruCallback = func(k, v interface{}) bool { return true }
+	l.Iterate(f)
+	l.Put("b", 2, 1)
+	if deleted != "a" {
+		t.Fatal("expecting a deleted, but ", deleted)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	var dk string
+	var dv int
+	l := NewTypedLru(3, time.Hour, func(k string, v int) {
+		dk, dv = k, v
+	})
+	l.Put("a", 1, 1)
+	l.Put("b", 2, 1)
+	l.Put("c", 3, 2)
+	if dk != "a" || dv != 1 || l.Len() != 2 {
+		t.Fatal("expecting a=1 is pulled out, but got ", dk, "=", dv)
+	}
+
+	v := l.Get("b")
+	if v == nil || v.Key() != "b" || v.Val() != 2 || v.Size() != 1 {
+		t.Fatal("expecting b=2, but got ", v)
+	}
+
+	m := l.GetData()
+	if len(m) != 2 || m["b"] != 2 || m["c"] != 3 {
+		t.Fatal("wrong data ", m)
+	}
+
+	l.Delete("c")
+	if dk != "c" || dv != 3 || l.Peek("c") != nil {
+		t.Fatal("expecting c=3 is deleted")
+	}
+}
+
+func TestPutWithTTL(t *testing.T) {
+	now := time.Now()
+	clck := func() time.Time {
+		return now
+	}
+
+	var deleted []int
+	l := NewLruWithClock(1000, 0, func(k, v int) {
+		deleted = append(deleted, k)
+	}, clck)
+	l.PutWithTTL(1, 1, 1, 30*time.Millisecond)
+	l.PutWithTTL(2, 2, 1, 10*time.Millisecond)
+	l.Put(3, 3, 1)
+	if l.Peek(1).ExpiresAt().IsZero() || !l.Peek(3).ExpiresAt().IsZero() {
+		t.Fatal("only 1 and 2 must have own deadline")
+	}
+
+	now = now.Add(15 * time.Millisecond)
+	l.SweepByTime()
+	if l.Len() != 2 || l.Get(2) != nil || len(deleted) != 1 || deleted[0] != 2 {
+		t.Fatal("only 2 must be expired, deleted=", deleted)
+	}
+
+	// Get doesn't prolong own deadline
+	l.Get(1)
+	now = now.Add(20 * time.Millisecond)
+	l.SweepByTime()
+	if l.Len() != 1 || l.Get(3) == nil || len(deleted) != 2 || deleted[1] != 1 {
+		t.Fatal("only 3 must stay, deleted=", deleted)
+	}
+}
+
+func TestPutWithTTLReplace(t *testing.T) {
+	now := time.Now()
+	l := NewLruWithClock[int, int](2, time.Hour, nil, func() time.Time { return now })
+	l.PutWithTTL(1, 1, 1, time.Millisecond)
+	l.PutWithTTL(2, 2, 1, time.Hour)
+	l.Put(1, 1, 1)
+	l.Put(3, 3, 1)
+	l.Delete(1)
+	if len(l.ttlHeap) != 0 {
+		t.Fatal("ttl heap must be empty, but len=", len(l.ttlHeap))
+	}
+
+	now = now.Add(2 * time.Millisecond)
+	l.SweepByTime()
+	if l.Len() != 1 || l.Peek(3) == nil {
+		t.Fatal("expecting only 3 in the cache")
+	}
+}
+
+func TestLruWithClock(t *testing.T) {
+	now := time.Now()
+	clck := func() time.Time {
+		return now
+	}
+
+	var deleted []int
+	l := NewLruWithClock(1000, time.Second, func(k, v int) {
+		deleted = append(deleted, k)
+	}, clck)
+	l.Put(1, 1, 1)
+	now = now.Add(500 * time.Millisecond)
+	l.Put(2, 2, 1)
+	l.PutWithTTL(3, 3, 1, 100*time.Millisecond)
+	if l.Peek(1).TouchedAt() != now.Add(-500*time.Millisecond) || l.Peek(2).TouchedAt() != now {
+		t.Fatal("wrong touch times")
+	}
+
+	now = now.Add(101 * time.Millisecond)
+	l.SweepByTime()
+	if len(deleted) != 1 || deleted[0] != 3 {
+		t.Fatal("expecting 3 is expired, but deleted=", deleted)
+	}
+
+	now = now.Add(400 * time.Millisecond)
+	if l.Get(1) != nil || l.Len() != 1 || len(deleted) != 2 || deleted[1] != 1 {
+		t.Fatal("expecting 1 is expired, but deleted=", deleted)
+	}
+
+	// exactly maxDur is not expired yet
+	now = now.Add(499 * time.Millisecond)
+	if l.Get(2) == nil {
+		t.Fatal("2 must not be expired yet")
+	}
+}
+
+func TestEvictCallback(t *testing.T) {
+	now := time.Now()
+	var reasons []LruEvictReason
+	var sizes []int64
+	l := NewLruWithClock[int, int](3, time.Second, nil, func() time.Time { return now })
+	l.SetEvictCallback(func(k, v int, size int64, r LruEvictReason) {
+		reasons = append(reasons, r)
+		sizes = append(sizes, size)
+	})
+	l.Put(1, 1, 1)
+	l.Put(1, 1, 2)
+	l.Put(2, 2, 1)
+	l.Put(3, 3, 1)
+	l.Delete(2)
+	l.DeleteNoCallback(3)
+	l.Put(4, 4, 1)
+	now = now.Add(2 * time.Second)
+	l.Put(5, 5, 1)
+	l.Put(6, 6, 1)
+	l.Clear(true)
+
+	exp := []LruEvictReason{LruReplaced, LruCapacity, LruDeleted, LruExpired, LruCleared, LruCleared}
+	expSz := []int64{1, 2, 1, 1, 1, 1}
+	if !reflect.DeepEqual(reasons, exp) || !reflect.DeepEqual(sizes, expSz) {
+		t.Fatal("expecting ", exp, expSz, ", but got ", reasons, sizes)
+	}
+	if LruCapacity.String() != "Capacity" || LruEvictReason(10).String() != "LruEvictReason(10)" {
+		t.Fatal("wrong String() ", LruCapacity, " ", LruEvictReason(10))
+	}
+}
+
+func TestPool(t *testing.T) {
+	l := NewTypedLru[int, int](10, time.Hour, nil)
+	l.SetPoolSize(5)
+	for i := 0; i < 10; i++ {
+		l.Put(i, i, 1)
+	}
+	for i := 0; i < 10; i++ {
+		l.Delete(i)
+	}
+	if l.pool.len != 5 {
+		t.Fatal("expecting 5 elements in the pool, but ", l.pool.len)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Put(i, i, 1)
+	}
+	if l.pool.len != 2 || l.Len() != 3 || l.Peek(2).Val() != 2 {
+		t.Fatal("expecting 2 elements in the pool, but ", l.pool.len)
+	}
+
+	l.SetPoolSize(1)
+	if l.pool.len != 1 || l.pool.head.next != nil {
+		t.Fatal("expecting 1 element in the pool, but ", l.pool.len)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Delete(1)
+		l.Put(1, 1, 1)
+	})
+	if allocs != 0 {
+		t.Fatal("expecting no allocations, but ", allocs)
+	}
+}
+
+func TestPoolNegativeSize(t *testing.T) {
+	l := NewTypedLru[int, int](10, time.Hour, nil)
+	l.Put(1, 1, 1)
+	l.Delete(1)
+	l.SetPoolSize(-1)
+	if l.pool.len != 0 || l.pool.head != nil {
+		t.Fatal("expecting empty pool, but ", l.pool.len)
+	}
+	l.Put(1, 1, 1)
+	l.Delete(1)
+	if l.pool.len != 0 {
+		t.Fatal("expecting empty pool, but ", l.pool.len)
+	}
+}
@@ -1,33 +1,51 @@
 package container
 
 type (
-	// RingBuffer - the ring buffer with fixed capacity. The container has
+	// TypedRingBuffer - the ring buffer of values of type T with fixed capacity. The container has
 	// head and tail. It provides operations that allows to manipulate the
 	// data stored in the buffer.
 	//
 	// Handle with caution! The buffer doesn't free elements, and doesn't nilling
 	// them intentionally. It was made with an intention to minimize memory allocations
 	// for the stored elements. It is the buffer's user responsibility to free
-	// and nillify stored values.
-	RingBuffer struct {
-		v []interface{}
-		h int
-		n int
+	// and nillify stored values, or to turn the zeroing on (see SetZeroing).
+	TypedRingBuffer[T any] struct {
+		v    []T
+		h    int
+		n    int
+		zero bool
 	}
+
+	// RingBuffer - the ring buffer of interface{} values
+	RingBuffer = TypedRingBuffer[interface{}]
 )
 
-// NewRingBuffer - returns new ring buffer with size elements reserved
+// NewRingBuffer - returns new ring buffer of interface{} values with size
+// elements reserved
 func NewRingBuffer(size int) *RingBuffer {
+	return NewTypedRingBuffer[interface{}](size)
+}
+
+// NewTypedRingBuffer - same as NewRingBuffer, but for the values of type T
+func NewTypedRingBuffer[T any](size int) *TypedRingBuffer[T] {
 	if size < 1 {
 		panic("size must be positive")
 	}
-	rb := new(RingBuffer)
-	rb.v = make([]interface{}, size, size)
+	rb := new(TypedRingBuffer[T])
+	rb.v = make([]T, size, size)
 	return rb
 }
 
+// SetZeroing - turns on or off the zeroing mode. In the mode the buffer
+// assigns zero value to the slots released by AdvanceHead and Clear, so the
+// released values can be collected by GC. The slots reused by AdvanceTail
+// are not zeroed, so the values can be reused the same way as without the mode.
+func (rb *TypedRingBuffer[T]) SetZeroing(zero bool) {
+	rb.zero = zero
+}
+
 // Head - returns head's element. Will panic if size of the RingBuffer is 0
-func (rb *RingBuffer) Head() interface{} {
+func (rb *TypedRingBuffer[T]) Head() T {
 	if rb.n == 0 {
 		panic("Buffer is empty")
 	}
@@ -35,7 +53,7 @@ func (rb *RingBuffer) Head() interface{} {
 }
 
 // Tail - returns tail's element. Will panic if size of the RingBuffer is 0
-func (rb *RingBuffer) Tail() interface{} {
+func (rb *TypedRingBuffer[T]) Tail() T {
 	if rb.n == 0 {
 		panic("Buffer is empty")
 	}
@@ -44,31 +62,31 @@ func (rb *RingBuffer) Tail() interface{} {
 
 // At - returns element at the index i, countin from the head. Will panic if
 // the index is out of bounds
-func (rb *RingBuffer) At(i int) interface{} {
+func (rb *TypedRingBuffer[T]) At(i int) T {
 	rb.checkIdx(i)
 	return rb.v[rb.getIdx(rb.h+i)]
 }
 
 // Set - assign value v for the element i, counting from the head.
-func (rb *RingBuffer) Set(i int, v interface{}) {
+func (rb *TypedRingBuffer[T]) Set(i int, v T) {
 	rb.checkIdx(i)
 	rb.v[rb.getIdx(rb.h+i)] = v
 }
 
 // Len - returns current buffer size
-func (rb *RingBuffer) Len() int {
+func (rb *TypedRingBuffer[T]) Len() int {
 	return rb.n
 }
 
 // Capacity - returns the buffer capacity
-func (rb *RingBuffer) Capacity() int {
+func (rb *TypedRingBuffer[T]) Capacity() int {
 	return len(rb.v)
 }
 
 // AdvanceTail - moves the tail and increases the current buffer size by 1.
 // if the buffer size reaches the maximum capacity, it will return head element,
 // moving the head and tail both to 1 position.
-func (rb *RingBuffer) AdvanceTail() interface{} {
+func (rb *TypedRingBuffer[T]) AdvanceTail() T {
 	if rb.n == len(rb.v) {
 		rb.h = rb.getIdx(rb.h + 1)
 	} else {
@@ -79,12 +97,16 @@ func (rb *RingBuffer) AdvanceTail() interface{} {
 
 // AdvanceHead - advances head and reduce the buffer size to 1 (head) element.
 // It returns the element, which was at head, before the operation
-func (rb *RingBuffer) AdvanceHead() interface{} {
+func (rb *TypedRingBuffer[T]) AdvanceHead() T {
 	if rb.n < 1 {
 		panic("The buffer is empty")
 	}
 	rb.n--
 	v := rb.v[rb.h]
+	if rb.zero {
+		var zv T
+		rb.v[rb.h] = zv
+	}
 	rb.h = rb.getIdx(rb.h + 1)
 	return v
 }
@@ -93,24 +115,28 @@ func (rb *RingBuffer) AdvanceHead() interface{} {
 // head element if the buffer's capacity is reached. The previos element stored
 // at the new tail position is returned. After the operation tail points to the
 // new value v.
-func (rb *RingBuffer) Push(v interface{}) interface{} {
+func (rb *TypedRingBuffer[T]) Push(v T) T {
 	r := rb.AdvanceTail()
 	rb.v[rb.getIdx(rb.h+rb.n-1)] = v
 	return r
 }
 
 // IsFull - returns true if the buffer is full Len() == Capacity()
-func (rb *RingBuffer) IsFull() bool {
+func (rb *TypedRingBuffer[T]) IsFull() bool {
 	return rb.n == len(rb.v)
 }
 
-// Clear - drops the buffer size to 0
-func (rb *RingBuffer) Clear() {
+// Clear - drops the buffer size to 0. In the zeroing mode all the slots are
+// zeroed
+func (rb *TypedRingBuffer[T]) Clear() {
+	if rb.zero {
+		clear(rb.v)
+	}
 	rb.h = 0
 	rb.n = 0
 }
 
-func (rb *RingBuffer) getIdx(i int) int {
+func (rb *TypedRingBuffer[T]) getIdx(i int) int {
 	if i >= len(rb.v) {
 		return i - len(rb.v)
 	}
@@ -120,7 +146,7 @@ func (rb *RingBuffer) getIdx(i int) int {
 	return i
 }
 
-func (rb *RingBuffer) checkIdx(i int) {
+func (rb *TypedRingBuffer[T]) checkIdx(i int) {
 	if i < 0 || i >= rb.n {
 		panic("Index out of bounds")
 	}
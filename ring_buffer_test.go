@@ -39,6 +39,53 @@ func TestGeneral(t *testing.T) {
 	}
 }
 
+func TestTypedRingBuffer(t *testing.T) {
+	r := NewTypedRingBuffer[string](2)
+	r.Push("a")
+	r.Push("b")
+	if r.Push("c") != "a" || r.Head() != "b" || r.Tail() != "c" {
+		t.Fatal("Wrong values ", r.v)
+	}
+
+	// AdvanceTail returns the slot value for reuse
+	if r.AdvanceTail() != "b" || r.Head() != "c" || r.Tail() != "b" {
+		t.Fatal("Wrong values ", r.v)
+	}
+	if r.AdvanceHead() != "c" || r.v[0] != "c" {
+		t.Fatal("the slot must not be zeroed by default ", r.v)
+	}
+
+	// RingBuffer is the untyped buffer
+	var rb *RingBuffer = NewRingBuffer(1)
+	var tr *TypedRingBuffer[interface{}] = rb
+	if tr.Push(1) != nil || rb.Head().(int) != 1 {
+		t.Fatal("Wrong values ", rb.v)
+	}
+}
+
+func TestZeroing(t *testing.T) {
+	r := NewTypedRingBuffer[*int](3)
+	r.SetZeroing(true)
+	for i := 0; i < 3; i++ {
+		v := i
+		r.Push(&v)
+	}
+
+	if v := r.AdvanceHead(); *v != 0 || r.v[0] != nil {
+		t.Fatal("the head slot must be zeroed ", r.v)
+	}
+	if r.AdvanceTail() != nil || r.Len() != 3 {
+		t.Fatal("the released slot must be zero ", r.v)
+	}
+
+	r.Clear()
+	for i := range r.v {
+		if r.v[i] != nil {
+			t.Fatal("all the slots must be zeroed ", r.v)
+		}
+	}
+}
+
 func TestPanicing(t *testing.T) {
 	if !catch(func() { NewRingBuffer(0) }) {
 		t.Fatal("Expecting panic - wrong size")
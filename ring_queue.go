@@ -0,0 +1,219 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type (
+	// RingQueue is the concurrency-safe bounded FIFO queue on top of
+	// RingBuffer. The values are put to the tail and taken from the head,
+	// what happens when the queue is full is defined by RingQueueOverflow.
+	RingQueue[T any] struct {
+		lock     sync.Mutex
+		rb       *TypedRingBuffer[T]
+		overflow RingQueueOverflow
+		closed   bool
+		dropped  int64
+
+		// ch is closed and replaced when the queue state changes, so the
+		// goroutines waiting on it can check the state again. waiters is the
+		// number of such goroutines.
+		ch      chan struct{}
+		waiters int
+	}
+
+	// RingQueueOverflow defines the behavior of RingQueue Put operations when
+	// the queue is full
+	RingQueueOverflow int
+)
+
+const (
+	// RqBlock makes the Put operations wait until there is space in the queue
+	RqBlock RingQueueOverflow = iota
+	// RqDropNewest makes the Put operations drop the value being put
+	RqDropNewest
+	// RqOverwriteOldest makes the Put operations drop the value at the head,
+	// the same way as RingBuffer.Push does
+	RqOverwriteOldest
+)
+
+// ErrRingQueueClosed is returned by the RingQueue operations when the queue is
+// closed
+var ErrRingQueueClosed = errors.New("the queue is closed")
+
+// NewRingQueue creates new RingQueue with capacity size and the overflow
+// policy. The queue zeroes the slots of the taken values (see
+// RingBuffer.SetZeroing), so the values can be collected by GC.
+func NewRingQueue[T any](size int, overflow RingQueueOverflow) *RingQueue[T] {
+	q := new(RingQueue[T])
+	q.rb = NewTypedRingBuffer[T](size)
+	q.rb.SetZeroing(true)
+	q.overflow = overflow
+	q.ch = make(chan struct{})
+	return q
+}
+
+// Put places v to the tail of the queue. When the queue is full, it waits
+// until there is space, drops v, or drops the head value depending on the
+// overflow policy. It returns ErrRingQueueClosed if the queue is closed.
+func (q *RingQueue[T]) Put(v T) error {
+	return q.PutCtx(context.Background(), v)
+}
+
+// PutCtx same as Put, but stops waiting and returns ctx.Err() when ctx is
+// done
+func (q *RingQueue[T]) PutCtx(ctx context.Context, v T) error {
+	q.lock.Lock()
+	for {
+		if q.closed {
+			q.lock.Unlock()
+			return ErrRingQueueClosed
+		}
+		if q.overflow != RqBlock || !q.rb.IsFull() {
+			q.put(v)
+			q.lock.Unlock()
+			return nil
+		}
+		if err := q.wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// TryPut places v to the tail of the queue without waiting. It returns false
+// if the queue is closed, or v is not put because the queue is full.
+func (q *RingQueue[T]) TryPut(v T) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed || (q.overflow == RqBlock && q.rb.IsFull()) {
+		return false
+	}
+	return q.put(v)
+}
+
+// Take removes and returns the value from the head of the queue. It waits
+// until the queue is not empty. The values put before Close can be taken after
+// it, ErrRingQueueClosed is returned when the closed queue is empty.
+func (q *RingQueue[T]) Take() (T, error) {
+	return q.TakeCtx(context.Background())
+}
+
+// TakeCtx same as Take, but stops waiting and returns ctx.Err() when ctx is
+// done
+func (q *RingQueue[T]) TakeCtx(ctx context.Context) (T, error) {
+	var zv T
+	q.lock.Lock()
+	for {
+		if q.rb.Len() > 0 {
+			v := q.take()
+			q.lock.Unlock()
+			return v, nil
+		}
+		if q.closed {
+			q.lock.Unlock()
+			return zv, ErrRingQueueClosed
+		}
+		if err := q.wait(ctx); err != nil {
+			return zv, err
+		}
+	}
+}
+
+// TryTake removes and returns the value from the head of the queue without
+// waiting. The second returned value is false if the queue is empty.
+func (q *RingQueue[T]) TryTake() (T, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.rb.Len() == 0 {
+		var zv T
+		return zv, false
+	}
+	return q.take(), true
+}
+
+// Close closes the queue and wakes up all the waiting goroutines. The Put
+// operations fail after that, the Take operations return the values left in
+// the queue.
+func (q *RingQueue[T]) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.signal()
+	}
+	return nil
+}
+
+// Len returns number of the values in the queue
+func (q *RingQueue[T]) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.rb.Len()
+}
+
+// Capacity returns the queue capacity
+func (q *RingQueue[T]) Capacity() int {
+	return q.rb.Capacity()
+}
+
+// Dropped returns number of the values dropped because the queue was full
+func (q *RingQueue[T]) Dropped() int64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.dropped
+}
+
+// put places v according to the overflow policy, it returns false if v is
+// dropped. The lock must be held.
+func (q *RingQueue[T]) put(v T) bool {
+	if q.rb.IsFull() {
+		q.dropped++
+		if q.overflow == RqDropNewest {
+			return false
+		}
+	}
+	q.rb.Push(v)
+	q.signal()
+	return true
+}
+
+// take removes the head value, the lock must be held and the queue must not
+// be empty
+func (q *RingQueue[T]) take() T {
+	v := q.rb.AdvanceHead()
+	q.signal()
+	return v
+}
+
+// wait releases the lock and waits until the queue state is changed or ctx is
+// done. The lock is held again when wait returns nil, and is not held when it
+// returns an error.
+func (q *RingQueue[T]) wait(ctx context.Context) error {
+	ch := q.ch
+	q.waiters++
+	q.lock.Unlock()
+
+	var err error
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.lock.Lock()
+	q.waiters--
+	if err != nil {
+		q.lock.Unlock()
+	}
+	return err
+}
+
+// signal wakes up the waiting goroutines, the lock must be held
+func (q *RingQueue[T]) signal() {
+	if q.waiters > 0 {
+		close(q.ch)
+		q.ch = make(chan struct{})
+	}
+}
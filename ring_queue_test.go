@@ -0,0 +1,146 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRingQueueBlock(t *testing.T) {
+	q := NewRingQueue[int](2, RqBlock)
+	q.Put(1)
+	if !q.TryPut(2) || q.TryPut(3) || q.Len() != 2 {
+		t.Fatal("expecting 2 values in the full queue, but ", q.Len())
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- q.Put(3)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Put must wait for space")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if v, err := q.Take(); v != 1 || err != nil {
+		t.Fatal("expecting 1, but ", v, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	if v, ok := q.TryTake(); v != 2 || !ok {
+		t.Fatal("expecting 2, but ", v)
+	}
+	if v, ok := q.TryTake(); v != 3 || !ok {
+		t.Fatal("expecting 3, but ", v)
+	}
+	if _, ok := q.TryTake(); ok || q.Dropped() != 0 {
+		t.Fatal("the queue must be empty")
+	}
+}
+
+func TestRingQueueOverflow(t *testing.T) {
+	q := NewRingQueue[int](2, RqDropNewest)
+	for i := 1; i <= 3; i++ {
+		q.Put(i)
+	}
+	if q.TryPut(4) || q.Dropped() != 2 || q.Len() != 2 {
+		t.Fatal("expecting 2 dropped, but ", q.Dropped())
+	}
+	if v, _ := q.Take(); v != 1 {
+		t.Fatal("expecting 1, but ", v)
+	}
+
+	q = NewRingQueue[int](2, RqOverwriteOldest)
+	for i := 1; i <= 3; i++ {
+		q.Put(i)
+	}
+	if !q.TryPut(4) || q.Dropped() != 2 || q.Len() != 2 {
+		t.Fatal("expecting 2 dropped, but ", q.Dropped())
+	}
+	if v, _ := q.Take(); v != 3 {
+		t.Fatal("expecting 3, but ", v)
+	}
+}
+
+func TestRingQueueCtx(t *testing.T) {
+	q := NewRingQueue[int](1, RqBlock)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.TakeCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expecting deadline, but ", err)
+	}
+
+	q.Put(1)
+	if err := q.PutCtx(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatal("expecting deadline, but ", err)
+	}
+	if q.Len() != 1 {
+		t.Fatal("expecting 1 value, but ", q.Len())
+	}
+}
+
+func TestRingQueueClose(t *testing.T) {
+	q := NewRingQueue[int](1, RqBlock)
+	q.Put(1)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- q.Put(2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+	if err := <-errs; err != ErrRingQueueClosed {
+		t.Fatal("expecting closed error, but ", err)
+	}
+
+	// the values put before Close are still available
+	if v, err := q.Take(); v != 1 || err != nil {
+		t.Fatal("expecting 1, but ", v, err)
+	}
+	if _, err := q.Take(); err != ErrRingQueueClosed {
+		t.Fatal("expecting closed error, but ", err)
+	}
+	if q.TryPut(3) || q.Close() != nil {
+		t.Fatal("the closed queue must not accept values")
+	}
+}
+
+func TestRingQueueConcurrent(t *testing.T) {
+	q := NewRingQueue[int](8, RqBlock)
+	const producers, count = 4, 1000
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; i++ {
+				q.Put(i)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		q.Close()
+	}()
+
+	sum, n := 0, 0
+	for {
+		v, err := q.Take()
+		if err != nil {
+			break
+		}
+		sum += v
+		n++
+	}
+	if n != producers*count || sum != producers*count*(count+1)/2 {
+		t.Fatal("expecting all the values taken, but ", n, sum)
+	}
+}
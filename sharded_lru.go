@@ -0,0 +1,178 @@
+package container
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+type (
+	// ShardedLru is the concurrency-safe Lru container. It distributes the keys
+	// over number of shards, every shard is an independent Lru protected by
+	// its own lock, so the operations over keys from different shards don't
+	// block each other. The maxSize is split between the shards evenly.
+	//
+	// The delete callback is always invoked when no shard lock is held, so it
+	// is safe to access the cache from the callback.
+	ShardedLru[K comparable, V any] struct {
+		seed   maphash.Seed
+		shards []lru_shard[K, V]
+		cback  TypedLruDeleteCallback[K, V]
+	}
+
+	lru_shard[K comparable, V any] struct {
+		lock sync.Mutex
+		lru  *TypedLru[K, V]
+		// deleted collects the elements pulled out of lru while the lock is held
+		deleted []TypedLruValue[K, V]
+	}
+)
+
+// NewShardedLru creates new ShardedLru container with n shards. maxSize, to
+// and cback have the same meaning as for NewTypedLru, the maxSize is the total
+// size of all shards.
+func NewShardedLru[K comparable, V any](n int, maxSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V]) *ShardedLru[K, V] {
+	if n < 1 {
+		panic("number of shards must be positive")
+	}
+	sl := new(ShardedLru[K, V])
+	sl.seed = maphash.MakeSeed()
+	sl.cback = cback
+	sl.shards = make([]lru_shard[K, V], n)
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		sz := maxSize / int64(n)
+		if int64(i) < maxSize%int64(n) {
+			sz++
+		}
+		var cb TypedLruDeleteCallback[K, V]
+		if cback != nil {
+			cb = s.onDelete
+		}
+		s.lru = NewTypedLru(sz, to, cb)
+	}
+	return sl
+}
+
+func (sl *ShardedLru[K, V]) Put(k K, v V, size int64) {
+	s := sl.shard(k)
+	s.lock.Lock()
+	s.lru.Put(k, v, size)
+	sl.unlock(s)
+}
+
+// Get returns a copy of the value found by k or nil, if there is no such key.
+func (sl *ShardedLru[K, V]) Get(k K) *TypedLruValue[K, V] {
+	s := sl.shard(k)
+	s.lock.Lock()
+	res := copyLruValue(s.lru.Get(k))
+	sl.unlock(s)
+	return res
+}
+
+// Peek same as Get, but doesn't affect the element position in the shard.
+func (sl *ShardedLru[K, V]) Peek(k K) *TypedLruValue[K, V] {
+	s := sl.shard(k)
+	s.lock.Lock()
+	res := copyLruValue(s.lru.Peek(k))
+	sl.unlock(s)
+	return res
+}
+
+func (sl *ShardedLru[K, V]) Delete(k K) {
+	s := sl.shard(k)
+	s.lock.Lock()
+	s.lru.Delete(k)
+	sl.unlock(s)
+}
+
+func (sl *ShardedLru[K, V]) Clear(cb bool) {
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		s.lru.Clear(cb)
+		sl.unlock(s)
+	}
+}
+
+// Iterate walks over the shards one by one and visits elements of every shard
+// in LRU order until f() returns false, or all elements are visited. The
+// shard is locked while its elements are visited.
+//
+// Note: the modifications of the container must not allowed in the f
+func (sl *ShardedLru[K, V]) Iterate(f TypedLruCallback[K, V]) {
+	stop := false
+	for i := 0; i < len(sl.shards) && !stop; i++ {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		s.lru.Iterate(func(k K, v V) bool {
+			stop = !f(k, v)
+			return !stop
+		})
+		s.lock.Unlock()
+	}
+}
+
+// SweepByTime pulls out expired elements from all shards
+func (sl *ShardedLru[K, V]) SweepByTime() {
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		s.lru.SweepByTime()
+		sl.unlock(s)
+	}
+}
+
+// Size returns total size of all shards
+func (sl *ShardedLru[K, V]) Size() int64 {
+	var res int64
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		res += s.lru.Size()
+		s.lock.Unlock()
+	}
+	return res
+}
+
+// Len returns total number of elements in all shards
+func (sl *ShardedLru[K, V]) Len() int {
+	res := 0
+	for i := range sl.shards {
+		s := &sl.shards[i]
+		s.lock.Lock()
+		res += s.lru.Len()
+		s.lock.Unlock()
+	}
+	return res
+}
+
+func (sl *ShardedLru[K, V]) shard(k K) *lru_shard[K, V] {
+	if len(sl.shards) == 1 {
+		return &sl.shards[0]
+	}
+	return &sl.shards[maphash.Comparable(sl.seed, k)%uint64(len(sl.shards))]
+}
+
+// unlock releases the shard lock and notifies the callback about the elements
+// deleted while the lock was held.
+func (sl *ShardedLru[K, V]) unlock(s *lru_shard[K, V]) {
+	deleted := s.deleted
+	s.deleted = nil
+	s.lock.Unlock()
+	for i := range deleted {
+		sl.cback(deleted[i].key, deleted[i].val)
+	}
+}
+
+func (s *lru_shard[K, V]) onDelete(k K, v V) {
+	s.deleted = append(s.deleted, TypedLruValue[K, V]{key: k, val: v})
+}
+
+func copyLruValue[K comparable, V any](v *TypedLruValue[K, V]) *TypedLruValue[K, V] {
+	if v == nil {
+		return nil
+	}
+	res := *v
+	return &res
+}
@@ -0,0 +1,113 @@
+package container
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedSimple(t *testing.T) {
+	l := NewShardedLru[int, int](4, 1000, time.Hour, nil)
+	for i := 0; i < 100; i++ {
+		l.Put(i, i*2, 1)
+	}
+	if l.Len() != 100 || l.Size() != 100 {
+		t.Fatal("expecting len=size=100, but len=", l.Len(), ", size=", l.Size())
+	}
+
+	for i := 0; i < 100; i++ {
+		v := l.Get(i)
+		if v == nil || v.Val() != i*2 {
+			t.Fatal("expecting ", i*2, ", but got ", v)
+		}
+	}
+
+	l.Delete(10)
+	if l.Peek(10) != nil || l.Len() != 99 {
+		t.Fatal("10 must be deleted")
+	}
+
+	cnt := 0
+	l.Iterate(func(k, v int) bool {
+		cnt++
+		return cnt < 50
+	})
+	if cnt != 50 {
+		t.Fatal("expecting 50 visited elements, but cnt=", cnt)
+	}
+}
+
+func TestShardedMaxSize(t *testing.T) {
+	l := NewShardedLru[int, int](3, 10, time.Hour, nil)
+	var total int64
+	for i := range l.shards {
+		total += l.shards[i].lru.maxSize
+	}
+	if total != 10 {
+		t.Fatal("expecting total size 10, but it is ", total)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.Put(i, i, 1)
+	}
+	if l.Size() > 10 {
+		t.Fatal("size must not exceed 10, but it is ", l.Size())
+	}
+}
+
+func TestShardedCallback(t *testing.T) {
+	var l *ShardedLru[int, int]
+	deleted := 0
+	l = NewShardedLru(2, 4, time.Hour, func(k, v int) {
+		// must not deadlock
+		if l.Peek(k) != nil || l.Len() > 4 {
+			t.Fatal("the deleted element must not be in the cache")
+		}
+		deleted++
+	})
+	for i := 0; i < 10; i++ {
+		l.Put(i, i, 1)
+	}
+	if deleted == 0 || deleted+l.Len() != 10 {
+		t.Fatal("expecting deleted+len=10, but deleted=", deleted, ", len=", l.Len())
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	var lock sync.Mutex
+	deleted := 0
+	l := NewShardedLru(8, 100, time.Hour, func(k, v int) {
+		lock.Lock()
+		deleted++
+		lock.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				l.Put(g*1000+i, i, 1)
+				l.Get(g*1000 + i/2)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if l.Len()+deleted != 8000 {
+		t.Fatal("expecting len+deleted=8000, but len=", l.Len(), ", deleted=", deleted)
+	}
+}
+
+func BenchmarkSharded(b *testing.B) {
+	l := NewShardedLru[int, int](16, 1000, time.Second, nil)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Put(i%1000, i, 1)
+			l.Get((i * 7) % 1000)
+			i++
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package container
+
+import (
+	"time"
+)
+
+type (
+	// Slru is the segmented "least recently used" container. It keeps
+	// elements in two segments - probation and protected. New elements are
+	// placed into the probation segment and they are moved to the protected one
+	// when they are accessed again. The elements pulled out of the protected
+	// segment, because it is overflown, are moved back to the probation one.
+	// When the cache is full, the elements are evicted from the probation
+	// segment first, so a single scan over many keys cannot flush the hot
+	// elements out of the cache.
+	//
+	// Slru has the same API as Lru (see LruCache) and controls elements by
+	// size, time of touch or both the same way.
+	Slru[K comparable, V any] struct {
+		// heads of the segments lists, see slruProbation and slruProtected
+		heads    [2]*lru_element[K, V]
+		sizes    [2]int64
+		pool     lru_pool[K, V]
+		kvMap    map[K]*lru_element[K, V]
+		maxSize  int64
+		protSize int64
+		maxDur   time.Duration
+		cback    TypedLruDeleteCallback[K, V]
+		ecback   LruEvictCallback[K, V]
+		clockNow TsClockNowF
+	}
+)
+
+const (
+	slruProbation = 0
+	slruProtected = 1
+)
+
+// NewSlru creates new Slru container with maximum size maxSize, where up to
+// protSize can be occupied by the protected segment. The to and cback
+// parameters have the same meaning as for NewTypedLru.
+func NewSlru[K comparable, V any](maxSize, protSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V]) *Slru[K, V] {
+	return NewSlruWithClock(maxSize, protSize, to, cback, time.Now)
+}
+
+// NewSlruWithClock same as NewSlru, but allows to provide the clock function
+// clck, which is used for discovering current time instead of time.Now()
+func NewSlruWithClock[K comparable, V any](maxSize, protSize int64, to time.Duration, cback TypedLruDeleteCallback[K, V], clck TsClockNowF) *Slru[K, V] {
+	if protSize > maxSize {
+		protSize = maxSize
+	}
+	s := new(Slru[K, V])
+	s.kvMap = make(map[K]*lru_element[K, V])
+	s.maxSize = maxSize
+	s.protSize = protSize
+	s.maxDur = to
+	s.cback = cback
+	s.clockNow = clck
+	s.pool.max = 1
+	return s
+}
+
+// SetPoolSize sets the maximum number of the deleted elements kept for reuse,
+// see Lru.SetPoolSize
+func (s *Slru[K, V]) SetPoolSize(n int) {
+	s.pool.setSize(n)
+}
+
+// SetEvictCallback sets the callback, see Lru.SetEvictCallback
+func (s *Slru[K, V]) SetEvictCallback(ecback LruEvictCallback[K, V]) {
+	s.ecback = ecback
+}
+
+// Put places the key-value pair into the probation segment. If the key is
+// already in the protected segment, the new value stays there.
+func (s *Slru[K, V]) Put(k K, v V, size int64) {
+	seg := uint8(slruProbation)
+	e, ok := s.kvMap[k]
+	if ok {
+		seg = e.seg
+		s.delete(e, LruReplaced, true)
+	}
+
+	tm := s.SweepByTime()
+	s.sweepBySize(size)
+
+	e = s.pool.get()
+	e.v.key = k
+	e.v.val = v
+	e.v.ts = tm
+	e.v.size = size
+	e.hidx = -1
+	s.add(e, seg)
+	s.kvMap[k] = e
+	s.balance()
+}
+
+// Get returns the value for the key k and moves it to the top of the
+// protected segment. Returns nil if there is no such key.
+func (s *Slru[K, V]) Get(k K) *TypedLruValue[K, V] {
+	ts := s.SweepByTime()
+	e, ok := s.kvMap[k]
+	if !ok {
+		return nil
+	}
+	if s.expired(e, ts) {
+		s.delete(e, LruExpired, true)
+		return nil
+	}
+	s.remove(e)
+	s.add(e, slruProtected)
+	e.v.ts = ts
+	s.balance()
+	return &e.v
+}
+
+func (s *Slru[K, V]) Peek(k K) *TypedLruValue[K, V] {
+	ts := s.SweepByTime()
+	e, ok := s.kvMap[k]
+	if !ok {
+		return nil
+	}
+	if s.expired(e, ts) {
+		s.delete(e, LruExpired, true)
+		return nil
+	}
+	return &e.v
+}
+
+func (s *Slru[K, V]) Delete(k K) {
+	s.SweepByTime()
+	if e, ok := s.kvMap[k]; ok {
+		s.delete(e, LruDeleted, true)
+	}
+}
+
+func (s *Slru[K, V]) DeleteNoCallback(k K) {
+	s.SweepByTime()
+	if e, ok := s.kvMap[k]; ok {
+		s.delete(e, LruDeleted, false)
+	}
+}
+
+func (s *Slru[K, V]) Clear(cb bool) {
+	for _, seg := range []int{slruProbation, slruProtected} {
+		for s.heads[seg] != nil {
+			s.delete(s.heads[seg], LruCleared, cb)
+		}
+	}
+}
+
+// Iterate walks over the protected segment elements and then over the
+// probation ones, both in LRU order. It calls f() for every key-value pair and
+// continues until the f() returns false, or all elements are visited.
+//
+// Note: the modifications of the container must not allowed in the f
+func (s *Slru[K, V]) Iterate(f TypedLruCallback[K, V]) {
+	for _, seg := range []int{slruProtected, slruProbation} {
+		head := s.heads[seg]
+		h := head
+		for h != nil {
+			if !f(h.v.key, h.v.val) {
+				return
+			}
+			h = h.next
+			if h == head {
+				break
+			}
+		}
+	}
+}
+
+func (s *Slru[K, V]) Size() int64 {
+	return s.sizes[slruProbation] + s.sizes[slruProtected]
+}
+
+// ProtectedSize returns size of the elements in the protected segment
+func (s *Slru[K, V]) ProtectedSize() int64 {
+	return s.sizes[slruProtected]
+}
+
+func (s *Slru[K, V]) Len() int {
+	return len(s.kvMap)
+}
+
+// SweepByTime pulls out the elements which were not touched for maxDur. Both
+// segments are ordered by the touch time, so the expired elements are at the
+// segments tails.
+func (s *Slru[K, V]) SweepByTime() time.Time {
+	if s.maxDur == 0 {
+		return nilTime
+	}
+	tm := s.clockNow()
+	for _, seg := range []int{slruProbation, slruProtected} {
+		for s.heads[seg] != nil && s.expired(s.heads[seg].prev, tm) {
+			s.delete(s.heads[seg].prev, LruExpired, true)
+		}
+	}
+	return tm
+}
+
+func (s *Slru[K, V]) expired(e *lru_element[K, V], tm time.Time) bool {
+	return s.maxDur > 0 && tm.Sub(e.v.ts) > s.maxDur
+}
+
+// sweepBySize evicts elements from the probation segment tail, and from the
+// protected one, if the probation segment is empty.
+func (s *Slru[K, V]) sweepBySize(addSize int64) {
+	for s.Size()+addSize > s.maxSize {
+		seg := slruProbation
+		if s.heads[seg] == nil {
+			seg = slruProtected
+		}
+		if s.heads[seg] == nil {
+			return
+		}
+		s.delete(s.heads[seg].prev, LruCapacity, true)
+	}
+}
+
+// balance moves the elements from the protected segment tail to the probation
+// segment while the protected segment is overflown.
+func (s *Slru[K, V]) balance() {
+	for s.sizes[slruProtected] > s.protSize && s.heads[slruProtected] != nil {
+		s.demote(s.heads[slruProtected].prev)
+	}
+}
+
+// demote moves the element to the probation segment. The element keeps its
+// touch time, so it is placed after the probation elements touched later to
+// keep the segment ordered by the touch time.
+func (s *Slru[K, V]) demote(e *lru_element[K, V]) {
+	s.remove(e)
+	head := s.heads[slruProbation]
+	if head == nil || !head.v.ts.After(e.v.ts) {
+		s.add(e, slruProbation)
+		return
+	}
+
+	// the demoted elements are usually old, so the place is searched from
+	// the tail, the head is touched after e, so the loop stops there
+	p := head.prev
+	for p.v.ts.Before(e.v.ts) {
+		p = p.prev
+	}
+	e.seg = slruProbation
+	e.prev = p
+	e.next = p.next
+	p.next.prev = e
+	p.next = e
+	s.sizes[slruProbation] += e.v.size
+}
+
+func (s *Slru[K, V]) add(e *lru_element[K, V], seg uint8) {
+	e.seg = seg
+	s.heads[seg] = addToHead(s.heads[seg], e)
+	s.sizes[seg] += e.v.size
+}
+
+func (s *Slru[K, V]) remove(e *lru_element[K, V]) {
+	s.heads[e.seg] = removeFromList(s.heads[e.seg], e)
+	s.sizes[e.seg] -= e.v.size
+}
+
+func (s *Slru[K, V]) delete(e *lru_element[K, V], r LruEvictReason, cb bool) {
+	s.remove(e)
+	delete(s.kvMap, e.v.key)
+	if cb && s.cback != nil {
+		s.cback(e.v.key, e.v.val)
+	}
+	if cb && s.ecback != nil {
+		s.ecback(e.v.key, e.v.val, e.v.size, r)
+	}
+	s.pool.put(e)
+}
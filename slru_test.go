@@ -0,0 +1,164 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var _ LruCache[int, int] = (*TypedLru[int, int])(nil)
+var _ LruCache[int, int] = (*Slru[int, int])(nil)
+
+func TestSlruScanResistance(t *testing.T) {
+	var deleted []int
+	s := NewSlru(10, 8, time.Hour, func(k, v int) {
+		deleted = append(deleted, k)
+	})
+	for i := 0; i < 5; i++ {
+		s.Put(i, i, 1)
+		s.Get(i)
+	}
+	if s.ProtectedSize() != 5 || s.Size() != 5 {
+		t.Fatal("expecting 5 protected elements, but ", s.ProtectedSize())
+	}
+
+	// the scan must not flush the hot elements
+	for i := 100; i < 200; i++ {
+		s.Put(i, i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		if s.Peek(i) == nil {
+			t.Fatal("hot element ", i, " must stay in the cache")
+		}
+	}
+	if s.Size() != 10 || len(deleted) != 95 || deleted[0] != 100 {
+		t.Fatal("wrong size=", s.Size(), " or deleted=", len(deleted))
+	}
+}
+
+func TestSlruDemotion(t *testing.T) {
+	s := NewSlru[int, int](4, 2, 0, nil)
+	s.Put(1, 1, 1)
+	s.Put(2, 2, 1)
+	s.Put(3, 3, 1)
+	s.Get(1)
+	s.Get(2)
+	s.Get(3) // 1 moves back to probation
+	if s.ProtectedSize() != 2 || s.heads[slruProbation].v.key != 1 {
+		t.Fatal("1 must be demoted to probation")
+	}
+
+	var keys []int
+	s.Iterate(func(k, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != 3 || keys[1] != 2 || keys[2] != 1 {
+		t.Fatal("wrong order ", keys)
+	}
+
+	s.Put(4, 4, 1)
+	s.Put(5, 5, 1)
+	if s.Peek(1) != nil || s.Len() != 4 {
+		t.Fatal("1 must be evicted")
+	}
+
+	// replacing keeps the protected element protected
+	s.Put(3, 33, 1)
+	if s.Peek(3).Val() != 33 || s.ProtectedSize() != 2 {
+		t.Fatal("3 must stay protected")
+	}
+
+	s.Delete(3)
+	s.Clear(true)
+	if s.Len() != 0 || s.Size() != 0 {
+		t.Fatal("must be empty")
+	}
+}
+
+func TestSlruTimeout(t *testing.T) {
+	now := time.Now()
+	deleted := 0
+	s := NewSlruWithClock(10, 5, time.Second, func(k, v int) {
+		deleted++
+	}, func() time.Time { return now })
+	s.Put(1, 1, 1)
+	s.Put(2, 2, 1)
+	s.Get(2)
+	now = now.Add(500 * time.Millisecond)
+	s.Put(3, 3, 1)
+	now = now.Add(501 * time.Millisecond)
+	s.SweepByTime()
+	if s.Len() != 1 || s.Peek(3) == nil || deleted != 2 {
+		t.Fatal("expecting 1 and 2 are expired")
+	}
+}
+
+func TestSlruTimeoutDemoted(t *testing.T) {
+	now := time.Now()
+	var deleted []int
+	s := NewSlruWithClock(10, 1, time.Second, func(k, v int) {
+		deleted = append(deleted, k)
+	}, func() time.Time { return now })
+	s.Put(1, 1, 1)
+	s.Get(1)
+	now = now.Add(500 * time.Millisecond)
+	s.Put(2, 2, 1)
+	s.Put(3, 3, 1)
+	s.Put(4, 4, 1)
+	s.Get(3)
+
+	// 1 is demoted, but it is older than 2 and 4 in the probation segment
+	if s.heads[slruProbation].prev.v.key != 1 {
+		t.Fatal("expecting 1 at the probation tail, but ", s.heads[slruProbation].prev.v.key)
+	}
+
+	now = now.Add(700 * time.Millisecond)
+	s.SweepByTime()
+	if s.Len() != 3 || s.Size() != 3 || len(deleted) != 1 || deleted[0] != 1 {
+		t.Fatal("expecting 1 is expired, but deleted=", deleted)
+	}
+
+	// the demoted element is placed between the elements touched before and
+	// after it
+	s = NewSlruWithClock[int, int](10, 1, time.Second, nil, func() time.Time { return now })
+	s.Put(1, 1, 1)
+	now = now.Add(100 * time.Millisecond)
+	s.Put(2, 2, 1)
+	s.Get(2)
+	now = now.Add(100 * time.Millisecond)
+	s.Put(3, 3, 1)
+	s.Put(4, 4, 1)
+	s.Get(4)
+	var keys []int
+	s.Iterate(func(k, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []int{4, 3, 2, 1}) {
+		t.Fatal("expecting 2 between 3 and 1, but ", keys)
+	}
+	now = now.Add(950 * time.Millisecond)
+	s.SweepByTime()
+	if s.Len() != 2 || s.Peek(1) != nil || s.Peek(2) != nil {
+		t.Fatal("expecting 1 and 2 are expired")
+	}
+}
+
+func TestSlruPool(t *testing.T) {
+	s := NewSlru[int, int](10, 5, time.Hour, nil)
+	s.SetPoolSize(3)
+	for i := 0; i < 5; i++ {
+		s.Put(i, i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		s.Delete(i)
+	}
+	if s.pool.len != 3 || s.pool.head.v.val != 0 {
+		t.Fatal("expecting 3 zeroed elements in the pool, but ", s.pool.len)
+	}
+	s.Put(1, 1, 1)
+	if s.pool.len != 2 || s.Peek(1).Val() != 1 {
+		t.Fatal("expecting 2 elements in the pool, but ", s.pool.len)
+	}
+}
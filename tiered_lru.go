@@ -0,0 +1,364 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kplr-io/container/btsbuf"
+)
+
+type (
+	// TieredLru is the two-level cache. The first level is the in-memory Lru,
+	// the elements it evicts by size are demoted to the second level, which is
+	// the file-backed segment store. The elements found in the second level by
+	// Get are promoted back to the memory. The levels have independent size
+	// limits.
+	//
+	// The segment store keeps the elements in append-only segment files as
+	// btsbuf-framed records (see Lru.Snapshot), and the index of the elements
+	// positions in memory. When the store size exceeds its limit, the oldest
+	// segment is dropped with all its elements. The segments without live
+	// elements are removed immediately, Compact rewrites the sparse ones.
+	//
+	// TieredLru is not thread-safe.
+	TieredLru[K comparable, V any] struct {
+		mem  *TypedLru[K, V]
+		disk lru_segments[K]
+		kc   LruCodec[K]
+		vc   LruCodec[V]
+		bbw  btsbuf.Writer
+		bbr  btsbuf.Reader
+		err  error
+	}
+
+	lru_segments[K comparable] struct {
+		dir     string
+		segSize int64
+		maxSize int64
+		size    int64
+		index   map[K]lru_disk_pos
+		// segs are ordered from the oldest to the current one
+		segs   []*lru_segment
+		nextId int
+		wbuf   []byte
+	}
+
+	lru_segment struct {
+		id   int
+		f    *os.File
+		size int64
+		live int64
+	}
+
+	lru_disk_pos struct {
+		seg  *lru_segment
+		offs int64
+		ln   int64
+	}
+)
+
+const lruSegmentExt = ".seg"
+
+// NewTieredLru creates new TieredLru with the memory level l and the segment
+// store in the directory dir. The segments files are up to segSize bytes, and
+// their total size is limited by maxDiskSize. The keys and values are stored
+// on disk being encoded by kc and vc. The existing segment files in dir are
+// removed. The l must not be used directly after that.
+//
+// The delete and evict callbacks of l are invoked when an element leaves the
+// memory level.
+func NewTieredLru[K comparable, V any](l *TypedLru[K, V], dir string, segSize, maxDiskSize int64, kc LruCodec[K], vc LruCodec[V]) (*TieredLru[K, V], error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	old, err := filepath.Glob(filepath.Join(dir, "*"+lruSegmentExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range old {
+		if err := os.Remove(fn); err != nil {
+			return nil, err
+		}
+	}
+
+	tl := new(TieredLru[K, V])
+	tl.mem = l
+	tl.kc = kc
+	tl.vc = vc
+	tl.disk.dir = dir
+	tl.disk.segSize = segSize
+	tl.disk.maxSize = maxDiskSize
+	tl.disk.index = make(map[K]lru_disk_pos)
+	l.evictHook = tl.onEvict
+	return tl, nil
+}
+
+// Put places the key-value pair into the memory level, the element with the
+// same key is dropped from the disk level
+func (tl *TieredLru[K, V]) Put(k K, v V, size int64) {
+	tl.PutWithTTL(k, v, size, 0)
+}
+
+// PutWithTTL same as Put, but the element gets its own deadline, see
+// Lru.PutWithTTL. The deadline is kept when the element is demoted.
+func (tl *TieredLru[K, V]) PutWithTTL(k K, v V, size int64, ttl time.Duration) {
+	tl.dropDisk(k)
+	tl.mem.PutWithTTL(k, v, size, ttl)
+}
+
+// Get returns the element from the memory level, or promotes it from the
+// disk level. Returns nil if there is no such key. The disk level elements
+// keep their touch times and own deadlines, the expired ones are dropped
+// instead of the promotion, the promoted ones keep the rest of their own TTL.
+// If the element could not be read from the disk, it is dropped and the error
+// is reported by Err().
+func (tl *TieredLru[K, V]) Get(k K) *TypedLruValue[K, V] {
+	if v := tl.mem.Get(k); v != nil {
+		return v
+	}
+	pos, ok := tl.disk.index[k]
+	if !ok {
+		return nil
+	}
+
+	buf := make([]byte, pos.ln)
+	_, err := pos.seg.f.ReadAt(buf, pos.offs+4)
+	tl.dropDisk(k)
+	if err != nil {
+		tl.err = err
+		return nil
+	}
+	rec, err := decodeLruRecord(&tl.bbr, buf)
+	if err != nil {
+		tl.err = err
+		return nil
+	}
+	now := tl.mem.clockNow()
+	if tl.mem.maxDur > 0 && now.Sub(rec.ts) > tl.mem.maxDur || !rec.exp.IsZero() && !now.Before(rec.exp) {
+		return nil
+	}
+	v, err := tl.vc.Decode(rec.val)
+	if err != nil {
+		tl.err = fmt.Errorf("could not decode value for key %v: %w", k, err)
+		return nil
+	}
+	var ttl time.Duration
+	if !rec.exp.IsZero() {
+		ttl = rec.exp.Sub(now)
+	}
+	tl.mem.PutWithTTL(k, v, rec.size, ttl)
+	return tl.mem.Peek(k)
+}
+
+// Delete deletes the key from both levels
+func (tl *TieredLru[K, V]) Delete(k K) {
+	tl.dropDisk(k)
+	tl.mem.Delete(k)
+}
+
+// Len returns number of elements in both levels
+func (tl *TieredLru[K, V]) Len() int {
+	return tl.mem.Len() + len(tl.disk.index)
+}
+
+// MemLen returns number of elements in the memory level
+func (tl *TieredLru[K, V]) MemLen() int {
+	return tl.mem.Len()
+}
+
+// DiskLen returns number of elements in the disk level
+func (tl *TieredLru[K, V]) DiskLen() int {
+	return len(tl.disk.index)
+}
+
+// DiskSize returns total size of the segment files
+func (tl *TieredLru[K, V]) DiskSize() int64 {
+	return tl.disk.size
+}
+
+// Err returns the last error happened while the elements were demoted or
+// promoted
+func (tl *TieredLru[K, V]) Err() error {
+	return tl.err
+}
+
+// Compact rewrites the segments where a half of the records or less are live,
+// the live records are moved to the current segment.
+func (tl *TieredLru[K, V]) Compact() error {
+	d := &tl.disk
+	for _, s := range append([]*lru_segment(nil), d.segs...) {
+		if s == d.current() || s.live*2 > s.size {
+			continue
+		}
+		buf, err := os.ReadFile(s.f.Name())
+		if err != nil {
+			return err
+		}
+		for offs := int64(0); offs+4 <= int64(len(buf)); {
+			ln := int64(binary.BigEndian.Uint32(buf[offs:]))
+			rec, err := decodeLruRecord(&tl.bbr, buf[offs+4:offs+4+ln])
+			if err != nil {
+				return err
+			}
+			k, err := tl.kc.Decode(rec.key)
+			if err != nil {
+				return err
+			}
+			if pos, ok := d.index[k]; ok && pos.seg == s && pos.offs == offs {
+				delete(d.index, k)
+				if err := d.write(k, buf[offs:offs+4+ln]); err != nil {
+					return err
+				}
+			}
+			offs += ln + 4
+		}
+		d.drop(s, nil)
+	}
+	return nil
+}
+
+// Close closes and removes the segment files. The memory level is not affected.
+func (tl *TieredLru[K, V]) Close() error {
+	var err error
+	for len(tl.disk.segs) > 0 {
+		if e := tl.disk.drop(tl.disk.segs[0], nil); e != nil && err == nil {
+			err = e
+		}
+	}
+	clear(tl.disk.index)
+	return err
+}
+
+// onEvict demotes the elements evicted from memory by size to the disk level
+// together with their touch times and own deadlines
+func (tl *TieredLru[K, V]) onEvict(lv *TypedLruValue[K, V], r LruEvictReason) {
+	if r != LruCapacity {
+		return
+	}
+	k, v := lv.key, lv.val
+	kb, err := tl.kc.Encode(k)
+	if err != nil {
+		tl.err = fmt.Errorf("could not encode key %v: %w", k, err)
+		return
+	}
+	vb, err := tl.vc.Encode(v)
+	if err != nil {
+		tl.err = fmt.Errorf("could not encode value for key %v: %w", k, err)
+		return
+	}
+	rec := encodeLruRecord(&tl.bbw, lru_record{size: lv.size, ts: lv.ts, exp: lv.exp, key: kb, val: vb})
+
+	d := &tl.disk
+	d.wbuf = binary.BigEndian.AppendUint32(d.wbuf[:0], uint32(len(rec)))
+	d.wbuf = append(d.wbuf, rec...)
+	if err := d.write(k, d.wbuf); err != nil {
+		tl.err = err
+		return
+	}
+	for d.size > d.maxSize && len(d.segs) > 0 {
+		if err := d.drop(d.segs[0], tl.dropKeys); err != nil {
+			tl.err = err
+		}
+	}
+}
+
+// dropKeys removes the index entries of the segment records
+func (tl *TieredLru[K, V]) dropKeys(s *lru_segment) error {
+	buf, err := os.ReadFile(s.f.Name())
+	if err != nil {
+		return err
+	}
+	for offs := int64(0); offs+4 <= int64(len(buf)); {
+		ln := int64(binary.BigEndian.Uint32(buf[offs:]))
+		rec, err := decodeLruRecord(&tl.bbr, buf[offs+4:offs+4+ln])
+		if err != nil {
+			return err
+		}
+		if k, err := tl.kc.Decode(rec.key); err == nil {
+			if pos, ok := tl.disk.index[k]; ok && pos.seg == s {
+				delete(tl.disk.index, k)
+			}
+		}
+		offs += ln + 4
+	}
+	return nil
+}
+
+// dropDisk removes the key from the disk level index. The segment is dropped
+// if it doesn't have live records anymore.
+func (tl *TieredLru[K, V]) dropDisk(k K) {
+	d := &tl.disk
+	pos, ok := d.index[k]
+	if !ok {
+		return
+	}
+	delete(d.index, k)
+	pos.seg.live -= pos.ln + 4
+	if pos.seg.live == 0 && pos.seg != d.current() {
+		if err := d.drop(pos.seg, nil); err != nil {
+			tl.err = err
+		}
+	}
+}
+
+func (d *lru_segments[K]) current() *lru_segment {
+	if len(d.segs) == 0 {
+		return nil
+	}
+	return d.segs[len(d.segs)-1]
+}
+
+// write appends the framed record rec for the key k to the current segment,
+// a new segment is started if the current one is full.
+func (d *lru_segments[K]) write(k K, rec []byte) error {
+	s := d.current()
+	if s == nil || s.size > 0 && s.size+int64(len(rec)) > d.segSize {
+		f, err := os.OpenFile(filepath.Join(d.dir, fmt.Sprintf("%08d%s", d.nextId, lruSegmentExt)), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		s = &lru_segment{id: d.nextId, f: f}
+		d.nextId++
+		d.segs = append(d.segs, s)
+	}
+
+	if _, err := s.f.WriteAt(rec, s.size); err != nil {
+		return err
+	}
+	d.index[k] = lru_disk_pos{seg: s, offs: s.size, ln: int64(len(rec)) - 4}
+	s.size += int64(len(rec))
+	s.live += int64(len(rec))
+	d.size += int64(len(rec))
+	return nil
+}
+
+// drop removes the segment file. If dropKeys is not nil, it is called for
+// removing the segment records from the index.
+func (d *lru_segments[K]) drop(s *lru_segment, dropKeys func(s *lru_segment) error) error {
+	var err error
+	if dropKeys != nil {
+		err = dropKeys(s)
+	}
+	for i := range d.segs {
+		if d.segs[i] == s {
+			d.segs = append(d.segs[:i], d.segs[i+1:]...)
+			break
+		}
+	}
+	d.size -= s.size
+	if e := s.f.Close(); e != nil && err == nil {
+		err = e
+	}
+	if e := os.Remove(s.f.Name()); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// SweepByTime sweeps the memory level, see Lru.SweepByTime
+func (tl *TieredLru[K, V]) SweepByTime() time.Time {
+	return tl.mem.SweepByTime()
+}
@@ -0,0 +1,137 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTieredLru(t *testing.T, memSize, segSize, diskSize int64) *TieredLru[string, string] {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "00000000"+lruSegmentExt), []byte("garbage"), 0644)
+	tl, err := NewTieredLru(NewTypedLru[string, string](memSize, time.Hour, nil), dir, segSize, diskSize, LruStringCodec{}, LruStringCodec{})
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	t.Cleanup(func() { tl.Close() })
+	return tl
+}
+
+func TestTieredLruDemotePromote(t *testing.T) {
+	tl := newTestTieredLru(t, 3, 1024, 1<<20)
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		tl.Put(k, "val-"+k, 1)
+	}
+	if tl.MemLen() != 3 || tl.DiskLen() != 2 || tl.Len() != 5 || tl.DiskSize() == 0 {
+		t.Fatal("expecting 3 in memory and 2 on disk, but ", tl.MemLen(), " and ", tl.DiskLen())
+	}
+
+	v := tl.Get("a")
+	if v == nil || v.Val() != "val-a" || v.Size() != 1 {
+		t.Fatal("a must be promoted, but got ", v)
+	}
+	// c is demoted
+	if tl.MemLen() != 3 || tl.DiskLen() != 2 || tl.mem.Peek("c") != nil || tl.disk.index["c"].seg == nil {
+		t.Fatal("c must be demoted")
+	}
+
+	tl.Put("b", "new-b", 1)
+	if tl.Get("b").Val() != "new-b" {
+		t.Fatal("b must be replaced")
+	}
+
+	tl.Delete("c")
+	tl.Delete("e")
+	if tl.Get("c") != nil || tl.Get("e") != nil || tl.Err() != nil {
+		t.Fatal("c and e must be deleted, err=", tl.Err())
+	}
+}
+
+func TestTieredLruExpiration(t *testing.T) {
+	now := time.Now()
+	clck := func() time.Time { return now }
+	tl, err := NewTieredLru(NewLruWithClock[string, string](1, time.Hour, nil, clck), t.TempDir(), 1024, 1<<20, LruStringCodec{}, LruStringCodec{})
+	if err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	defer tl.Close()
+
+	tl.PutWithTTL("a", "val-a", 1, 100*time.Millisecond)
+	exp := tl.mem.Peek("a").ExpiresAt()
+	tl.Put("b", "val-b", 1)
+
+	// a keeps its own deadline after the round trip
+	now = now.Add(50 * time.Millisecond)
+	if v := tl.Get("a"); v == nil || !v.ExpiresAt().Equal(exp) {
+		t.Fatal("a must be promoted with the same deadline, but ", v)
+	}
+	tl.Put("c", "val-c", 1)
+	now = now.Add(50 * time.Millisecond)
+	if tl.Get("a") != nil || tl.DiskLen() != 1 {
+		t.Fatal("a must be expired on disk")
+	}
+
+	// b keeps its touch time, so it is expired by maxDur
+	now = now.Add(time.Hour)
+	if tl.Get("b") != nil || tl.DiskLen() != 0 || tl.Err() != nil {
+		t.Fatal("b must be expired on disk, err=", tl.Err())
+	}
+}
+
+func TestTieredLruDiskLimit(t *testing.T) {
+	// every record is 4+(4+24)+(4+3)+(4+5) = 48 bytes, so there are 2 records
+	// per segment, and up to 4 records on disk
+	tl := newTestTieredLru(t, 1, 100, 200)
+	for i := 0; i < 10; i++ {
+		tl.Put(string(rune('a'+i))+"00", "val00", 1)
+	}
+	if tl.DiskSize() != 144 || len(tl.disk.segs) != 2 || tl.DiskLen() != 3 {
+		t.Fatal("wrong disk size=", tl.DiskSize(), " segs=", len(tl.disk.segs), " len=", tl.DiskLen())
+	}
+	if tl.Get("a00") != nil || tl.Get("i00") == nil || tl.Err() != nil {
+		t.Fatal("the oldest segments must be dropped, err=", tl.Err())
+	}
+}
+
+func TestTieredLruCompact(t *testing.T) {
+	tl := newTestTieredLru(t, 1, 100, 1<<20)
+	for i := 0; i < 7; i++ {
+		tl.Put(string(rune('a'+i)), "value", 1)
+	}
+	// 6 elements on disk in 3 segments
+	if len(tl.disk.segs) != 3 || tl.DiskLen() != 6 {
+		t.Fatal("expecting 3 segments, but ", len(tl.disk.segs))
+	}
+
+	// the first segment is dead and removed
+	tl.Delete("a")
+	tl.Delete("b")
+	if len(tl.disk.segs) != 2 {
+		t.Fatal("expecting 2 segments, but ", len(tl.disk.segs))
+	}
+
+	tl.Delete("c")
+	if err := tl.Compact(); err != nil {
+		t.Fatal("unexpected error ", err)
+	}
+	// d is moved to the new segment
+	if len(tl.disk.segs) != 2 || tl.disk.segs[0].id != 2 || tl.DiskLen() != 3 {
+		t.Fatal("the second segment must be compacted, segs=", len(tl.disk.segs))
+	}
+	for _, k := range []string{"d", "e", "f"} {
+		if v := tl.Get(k); v == nil || v.Val() != "value" {
+			t.Fatal("wrong value for ", k)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(tl.disk.dir, "*"+lruSegmentExt))
+	if len(files) != len(tl.disk.segs) {
+		t.Fatal("expecting ", len(tl.disk.segs), " files, but ", files)
+	}
+	tl.Close()
+	files, _ = filepath.Glob(filepath.Join(tl.disk.dir, "*"+lruSegmentExt))
+	if len(files) != 0 || tl.DiskLen() != 0 {
+		t.Fatal("the files must be removed ", files)
+	}
+}
@@ -0,0 +1,220 @@
+package container
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+type (
+	// TinyLfu is the admission filter which sits in front of Lru. It estimates
+	// the keys access frequencies by count-min sketch and doesn't let a new
+	// element into the cache if the element is accessed less frequently than
+	// the elements, which would be evicted to free space for it. It is useful
+	// when the elements sizes vary a lot, so one rarely used big element would
+	// evict many frequently used small ones.
+	//
+	// The sketch counters are halved periodically, so the old accesses are
+	// forgotten with time.
+	TinyLfu[K comparable, V any] struct {
+		lru    *TypedLru[K, V]
+		sketch cm_sketch
+		seed   maphash.Seed
+	}
+
+	// cm_sketch is the count-min sketch with 4 rows of 4-bit counters, two
+	// counters are packed into a byte
+	cm_sketch struct {
+		rows [4][]byte
+		mask uint64
+		// adds is the number of increments since the last aging, the counters
+		// are halved when it reaches sampleSize
+		adds       int
+		sampleSize int
+	}
+)
+
+const cmMaxCount = 15
+
+// NewTinyLfu creates new TinyLfu filter in front of l. The l must not be used
+// directly after that. counters is the expected number of distinct keys, the
+// sketch size is chosen by it.
+func NewTinyLfu[K comparable, V any](l *TypedLru[K, V], counters int) *TinyLfu[K, V] {
+	tl := new(TinyLfu[K, V])
+	tl.lru = l
+	tl.seed = maphash.MakeSeed()
+	tl.sketch.init(counters)
+	return tl
+}
+
+// Put places the key-value pair into the cache if it is admitted, see TryPut
+func (tl *TinyLfu[K, V]) Put(k K, v V, size int64) {
+	tl.TryPut(k, v, size)
+}
+
+// TryPut places the key-value pair into the cache if there is enough space for
+// it, the key is already in the cache, or the estimated key frequency is
+// higher than the summary frequency of the elements which would be evicted.
+// The element bigger than the cache maximum size is never admitted, if its key
+// is in the cache, the old value is deleted. TryPut returns whether the element
+// is admitted. The delete callback is not invoked for the rejected elements.
+func (tl *TinyLfu[K, V]) TryPut(k K, v V, size int64) bool {
+	h := maphash.Comparable(tl.seed, k)
+	tl.sketch.increment(h)
+	if !tl.admit(k, h, size) {
+		// the too big update is rejected, the old value is dropped then
+		if e, ok := tl.lru.kvMap[k]; ok {
+			tl.lru.delete(e, LruReplaced, true)
+		}
+		return false
+	}
+	tl.lru.Put(k, v, size)
+	return true
+}
+
+// Get returns the value for k and counts the access to k
+func (tl *TinyLfu[K, V]) Get(k K) *TypedLruValue[K, V] {
+	tl.sketch.increment(maphash.Comparable(tl.seed, k))
+	return tl.lru.Get(k)
+}
+
+func (tl *TinyLfu[K, V]) Peek(k K) *TypedLruValue[K, V] {
+	return tl.lru.Peek(k)
+}
+
+func (tl *TinyLfu[K, V]) Delete(k K) {
+	tl.lru.Delete(k)
+}
+
+func (tl *TinyLfu[K, V]) DeleteNoCallback(k K) {
+	tl.lru.DeleteNoCallback(k)
+}
+
+func (tl *TinyLfu[K, V]) Clear(cb bool) {
+	tl.lru.Clear(cb)
+}
+
+func (tl *TinyLfu[K, V]) Iterate(f TypedLruCallback[K, V]) {
+	tl.lru.Iterate(f)
+}
+
+func (tl *TinyLfu[K, V]) Size() int64 {
+	return tl.lru.Size()
+}
+
+func (tl *TinyLfu[K, V]) Len() int {
+	return tl.lru.Len()
+}
+
+func (tl *TinyLfu[K, V]) SweepByTime() time.Time {
+	return tl.lru.SweepByTime()
+}
+
+// Frequency returns estimated access frequency of the key k
+func (tl *TinyLfu[K, V]) Frequency(k K) int {
+	return tl.sketch.estimate(maphash.Comparable(tl.seed, k))
+}
+
+// Lru returns the underlying container
+func (tl *TinyLfu[K, V]) Lru() *TypedLru[K, V] {
+	return tl.lru
+}
+
+func (tl *TinyLfu[K, V]) admit(k K, h uint64, size int64) bool {
+	l := tl.lru
+	l.SweepByTime()
+	if size > l.maxSize {
+		return false
+	}
+	// the update is admitted even if it needs more space, the old value
+	// must not stay in the cache. So k is never among the victims below.
+	if _, ok := l.kvMap[k]; ok {
+		return true
+	}
+	free := l.maxSize - l.size
+	if size <= free || l.head == nil {
+		return true
+	}
+
+	freq := tl.sketch.estimate(h)
+	vfreq := 0
+	for e := l.head.prev; free < size; e = e.prev {
+		vfreq += tl.sketch.estimate(maphash.Comparable(tl.seed, e.v.key))
+		if vfreq >= freq {
+			return false
+		}
+		free += e.v.size
+		if e == l.head {
+			break
+		}
+	}
+	return true
+}
+
+func (cms *cm_sketch) init(counters int) {
+	w := 16
+	for w < counters {
+		w <<= 1
+	}
+	for i := range cms.rows {
+		cms.rows[i] = make([]byte, w/2)
+	}
+	cms.mask = uint64(w - 1)
+	cms.sampleSize = 10 * w
+}
+
+// index returns the counter index for the hash h in the row i
+func (cms *cm_sketch) index(h uint64, i int) uint64 {
+	h1 := h & 0xFFFFFFFF
+	h2 := h >> 32
+	return (h1 + uint64(i)*h2 + uint64(i*i)) & cms.mask
+}
+
+func (cms *cm_sketch) get(i int, idx uint64) int {
+	return int(cms.rows[i][idx/2]>>((idx&1)*4)) & 0x0F
+}
+
+func (cms *cm_sketch) increment(h uint64) {
+	min := cmMaxCount
+	var idxs [4]uint64
+	for i := range cms.rows {
+		idxs[i] = cms.index(h, i)
+		if c := cms.get(i, idxs[i]); c < min {
+			min = c
+		}
+	}
+	if min == cmMaxCount {
+		return
+	}
+
+	// conservative update: only the minimal counters are incremented
+	for i, idx := range idxs {
+		if cms.get(i, idx) == min {
+			cms.rows[i][idx/2] += 1 << ((idx & 1) * 4)
+		}
+	}
+
+	cms.adds++
+	if cms.adds >= cms.sampleSize {
+		cms.age()
+	}
+}
+
+func (cms *cm_sketch) estimate(h uint64) int {
+	min := cmMaxCount
+	for i := range cms.rows {
+		if c := cms.get(i, cms.index(h, i)); c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// age halves all the counters
+func (cms *cm_sketch) age() {
+	for _, row := range cms.rows {
+		for i, b := range row {
+			row[i] = (b >> 1) & 0x77
+		}
+	}
+	cms.adds /= 2
+}
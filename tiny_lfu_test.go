@@ -0,0 +1,128 @@
+package container
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var _ LruCache[int, int] = (*TinyLfu[int, int])(nil)
+
+func TestCmSketch(t *testing.T) {
+	var cms cm_sketch
+	cms.init(100)
+	for i := 0; i < 20; i++ {
+		cms.increment(1)
+	}
+	for i := 0; i < 3; i++ {
+		cms.increment(2)
+	}
+	if cms.estimate(1) != cmMaxCount || cms.estimate(2) != 3 || cms.estimate(3) != 0 {
+		t.Fatal("wrong estimates ", cms.estimate(1), " ", cms.estimate(2), " ", cms.estimate(3))
+	}
+
+	cms.age()
+	if cms.estimate(1) != cmMaxCount/2 || cms.estimate(2) != 1 {
+		t.Fatal("wrong estimates after aging ", cms.estimate(1), " ", cms.estimate(2))
+	}
+}
+
+func TestCmSketchAging(t *testing.T) {
+	var cms cm_sketch
+	cms.init(16)
+	for i := 0; i < 10; i++ {
+		cms.increment(1)
+	}
+	for i := 0; i < cms.sampleSize; i++ {
+		cms.increment(uint64(i + 100))
+	}
+	if cms.estimate(1) >= 10 {
+		t.Fatal("the counter must be aged, but it is ", cms.estimate(1))
+	}
+}
+
+func TestTinyLfuAdmission(t *testing.T) {
+	deleted := 0
+	tl := NewTinyLfu(NewTypedLru(10, time.Hour, func(k, v int) {
+		deleted++
+	}), 100)
+	for i := 0; i < 10; i++ {
+		tl.Put(i, i, 1)
+		for j := 0; j < 3; j++ {
+			tl.Get(i)
+		}
+	}
+
+	// the cold big element must not evict the hot small ones
+	if tl.TryPut(100, 100, 5) || tl.Len() != 10 || deleted != 0 {
+		t.Fatal("100 must be rejected")
+	}
+
+	// too big element is never admitted
+	if tl.TryPut(101, 101, 11) {
+		t.Fatal("101 must be rejected")
+	}
+
+	// frequently accessed element is admitted
+	for i := 0; i < 10; i++ {
+		tl.Get(102)
+	}
+	if !tl.TryPut(102, 102, 2) || tl.Peek(102) == nil || deleted != 2 {
+		t.Fatal("102 must be admitted")
+	}
+
+	// replacement is always admitted
+	if !tl.TryPut(5, 55, 1) || tl.Peek(5).Val() != 55 {
+		t.Fatal("5 must be replaced")
+	}
+
+	// even if it needs more space than the cold key would get
+	if !tl.TryPut(9, 99, 5) || tl.Peek(9).Val() != 99 || tl.Size() > 10 {
+		t.Fatal("9 must be replaced by the bigger value")
+	}
+
+	// too big replacement drops the old value
+	if tl.TryPut(9, 999, 11) || tl.Peek(9) != nil {
+		t.Fatal("9 must be deleted")
+	}
+}
+
+// skewedTrace returns zipf distributed keys with sizes, which vary from 1 to 128
+func skewedTrace(n int) []int {
+	r := rand.New(rand.NewSource(42))
+	z := rand.NewZipf(r, 1.1, 1, 100000)
+	res := make([]int, n)
+	for i := range res {
+		res[i] = int(z.Uint64())
+	}
+	return res
+}
+
+func traceSize(k int) int64 {
+	return 1 << uint((k*2654435761)%8)
+}
+
+func runSkewedTrace(b *testing.B, c LruCache[int, int], trace []int) {
+	hits := 0
+	for i := 0; i < b.N; i++ {
+		k := trace[i%len(trace)]
+		if c.Get(k) != nil {
+			hits++
+			continue
+		}
+		c.Put(k, k, traceSize(k))
+	}
+	b.ReportMetric(float64(hits)*100/float64(b.N), "hit%")
+}
+
+func BenchmarkSkewedLru(b *testing.B) {
+	trace := skewedTrace(1000000)
+	b.ResetTimer()
+	runSkewedTrace(b, NewTypedLru[int, int](50000, 0, nil), trace)
+}
+
+func BenchmarkSkewedTinyLfu(b *testing.B) {
+	trace := skewedTrace(1000000)
+	b.ResetTimer()
+	runSkewedTrace(b, NewTinyLfu(NewTypedLru[int, int](50000, 0, nil), 100000), trace)
+}